@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/projectdiscovery/gologger"
+)
+
+// interactiveHelp 交互命令说明
+const interactiveHelp = `Commands:
+  pause                     pause scheduling, in-flight attempts finish normally
+  resume                    resume scheduling
+  concurrency <G> <T>       set global and per-target concurrency
+  delay <100ms|200ms-1s>    set delay between requests to the same target
+  rate [layer] <rate[/burst]>
+                            set rate limit of a layer (global, host, subnet, protocol;
+                            default global), 0 to disable
+  status                    print current progress
+  help                      show this help`
+
+// runInteractive 从输入中读取控制命令，运行中调整引擎而无需重启扫描
+func runInteractive(ctx context.Context, engine *brute.Engine, input io.Reader) {
+	gologger.Info().Msg("Interactive control enabled, type 'help' for commands")
+
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := executeCommand(engine, strings.Fields(line)); err != nil {
+			gologger.Warning().Msgf("Command %q failed: %v", line, err)
+		}
+	}
+}
+
+// executeCommand 执行单条交互命令
+func executeCommand(engine *brute.Engine, fields []string) error {
+	switch command, args := strings.ToLower(fields[0]), fields[1:]; command {
+	case "pause":
+		engine.PauseScheduling()
+	case "resume":
+		engine.ResumeScheduling()
+	case "concurrency":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: concurrency <global> <per-target>")
+		}
+		targetConcurrent, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid global concurrency: %w", err)
+		}
+		taskConcurrent, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid per-target concurrency: %w", err)
+		}
+		return engine.SetConcurrency(targetConcurrent, taskConcurrent)
+	case "delay":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: delay <100ms|200ms-1s>")
+		}
+		minDelay, maxDelay, err := parseDelay(args[0])
+		if err != nil {
+			return err
+		}
+		return engine.SetDelay(minDelay, maxDelay)
+	case "rate":
+		layer := brute.RateLayerGlobal
+		switch len(args) {
+		case 1:
+		case 2:
+			layer = brute.RateLayer(strings.ToLower(args[0]))
+			if !slices.Contains(brute.RateLayers(), layer) {
+				return fmt.Errorf("unknown rate limit layer %q, expected one of %v", args[0], brute.RateLayers())
+			}
+			args = args[1:]
+		default:
+			return fmt.Errorf("usage: rate [global|host|subnet|protocol] <rate[/burst]>")
+		}
+		limit, err := brute.ParseRateLimit(args[0])
+		if err != nil {
+			return err
+		}
+		return engine.SetRateLimit(layer, limit)
+	case "status":
+		total, processed, success, failed, rate, elapsed := engine.GetProgressStats()
+		globalUsed, globalTotal, targetUsed, targetTotal := engine.GetConcurrencyStatus()
+		minDelay, maxDelay := engine.GetDelay()
+		gologger.Info().Msgf("Status: %d/%d | Success: %d | Failed: %d | Rate: %.2f/s | Elapsed: %v | Concurrency: G=%d/%d T=%d/%d | Delay: %v-%v | Paused: %v",
+			processed, total, success, failed, rate, elapsed.Truncate(time.Second), globalUsed, globalTotal, targetUsed, targetTotal,
+			minDelay, maxDelay, engine.IsPaused())
+	case "help":
+		fmt.Println(interactiveHelp)
+	default:
+		return fmt.Errorf("unknown command, type 'help' for commands")
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -49,41 +50,90 @@ type CLI struct {
 	Passwords    goflags.StringSlice `json:"passwords"`     // 密码列表
 	PassFile     string              `json:"pass_file"`     // 密码文件
 	UserPassFile string              `json:"userpass_file"` // 用户名:密码文件
+	Packs        goflags.StringSlice `json:"packs"`         // 指定的内置凭据包
+	RulesFile    string              `json:"rules_file"`    // 密码变形规则文件
 
 	// 爆破设置
-	TargetConcurrent int    `json:"target_concurrent"` // 目标并发数
-	TaskConcurrent   int    `json:"task_concurrent"`   // 任务并发数
-	Delay            string `json:"delay"`             // 延迟
-	Timeout          string `json:"timeout"`           // 超时
-	Retries          int    `json:"retries"`           // 重试次数
-	OkToStop         bool   `json:"ok_to_stop"`        // 成功后停止
+	TargetConcurrent int    `json:"target_concurrent"`  // 目标并发数
+	TaskConcurrent   int    `json:"task_concurrent"`    // 任务并发数
+	Delay            string `json:"delay"`              // 延迟，支持 最小值-最大值 形式的随机范围
+	Timeout          string `json:"timeout"`            // 超时
+	Retries          int    `json:"retries"`            // 重试次数
+	OkToStop         bool   `json:"ok_to_stop"`         // 成功后停止
+	NoVerify         bool   `json:"no_verify"`          // 禁用随机密码验证
+	NoUnauth         bool   `json:"no_unauth"`          // 禁用未授权访问探测
+	NoPreCheck       bool   `json:"no_precheck"`        // 禁用存活探测
+	FinishThreshold  int    `json:"finish_threshold"`   // 连续网络失败多少次后放弃目标
+	PreCheckTimeout  string `json:"precheck_timeout"`   // 存活探测超时
+	NoAdaptive       bool   `json:"no_adaptive"`        // 禁用自适应并发
+	AdaptiveMaxDelay string `json:"adaptive_max_delay"` // 自适应附加延迟的上限
+
+	// 会话复用设置
+	ReuseSessions   goflags.StringSlice `json:"reuse_sessions"`   // 启用会话复用的协议
+	SessionAttempts int                 `json:"session_attempts"` // 每个会话最多尝试的凭据数
+
+	// 限流设置，格式为 速率[/突发容量]
+	RateLimit         string `json:"rate_limit"`          // 全局速率
+	HostRateLimit     string `json:"host_rate_limit"`     // 每个主机的速率
+	SubnetRateLimit   string `json:"subnet_rate_limit"`   // 每个 /24 子网的速率
+	ProtocolRateLimit string `json:"protocol_rate_limit"` // 每个协议的速率
+
+	// 目标调度设置
+	TargetOrder      string `json:"target_order"`       // 目标调度顺序
+	MaxActiveTargets int    `json:"max_active_targets"` // 同时处理的目标数上限
+	ShuffleSeed      int    `json:"shuffle_seed"`       // 随机顺序的种子
+
+	// 密码喷洒设置
+	Strategy      string `json:"strategy"`        // 调度策略
+	SprayWindow   string `json:"spray_window"`    // 喷洒轮次之间的观察窗口
+	SprayPerRound int    `json:"spray_per_round"` // 每轮喷洒的密码数
+	Realm         string `json:"realm"`           // 所有目标共享的喷洒域
+
+	// 断点续传设置
+	CheckpointFile string `json:"checkpoint_file"` // 检查点文件
+	ResumeFile     string `json:"resume_file"`     // 恢复进度的检查点文件
 
 	// 空凭据设置
 	AllowBlankUsername bool `json:"allow_blank_username"` // 允许空用户名
 	AllowBlankPassword bool `json:"allow_blank_password"` // 允许空密码
+	PasswordOnly       bool `json:"password_only"`        // 所有协议都只尝试密码
 
 	// 输出设置
-	Output       string `json:"output"`        // 输出文件
-	Format       string `json:"format"`        // 输出格式
-	Verbose      bool   `json:"verbose"`       // 详细输出
-	Debug        bool   `json:"debug"`         // 调试模式
-	Silent       bool   `json:"silent"`        // 静默模式
-	NoColor      bool   `json:"no_color"`      // 禁用颜色
-	ShowFailed   bool   `json:"show_failed"`   // 显示失败结果
-	ShowProgress bool   `json:"show_progress"` // 显示进度条
+	Output        string `json:"output"`         // 输出文件
+	Format        string `json:"format"`         // 输出格式
+	Verbose       bool   `json:"verbose"`        // 详细输出
+	Debug         bool   `json:"debug"`          // 调试模式
+	Silent        bool   `json:"silent"`         // 静默模式
+	NoColor       bool   `json:"no_color"`       // 禁用颜色
+	ShowFailed    bool   `json:"show_failed"`    // 显示失败结果
+	ShowProgress  bool   `json:"show_progress"`  // 显示进度条
+	Interactive   bool   `json:"interactive"`    // 从标准输入读取控制命令
+	MetricsListen string `json:"metrics_listen"` // Prometheus 指标监听地址
+	TargetStats   bool   `json:"target_stats"`   // JSON 输出中包含每个目标的统计
+	Enrich        bool   `json:"enrich"`         // 认证成功后采集附加信息
 
 	// 其他设置
-	ConfigFile string `json:"config_file"` // 配置文件
-	Version    bool   `json:"version"`     // 显示版本
+	ConfigFile    string `json:"config_file"`    // 配置文件
+	Version       bool   `json:"version"`        // 显示版本
+	ListProtocols bool   `json:"list_protocols"` // 显示支持的协议
 }
 
 var (
 	successCount int32
 	failureCount int32
-	totalCount   int32
+	totalCount   int64
+	outputFile   *os.File // 结果输出文件，未指定时为 nil
 )
 
 func main() {
+	// 凭据包子命令，不解析爆破参数
+	if len(os.Args) > 1 && os.Args[1] == "packs" {
+		if err := runPacks(os.Stdout, os.Args[2:]); err != nil {
+			gologger.Fatal().Msgf("Failed to run packs command: %v", err)
+		}
+		return
+	}
+
 	// 解析命令行参数
 	cli, err := parseFlags()
 	if err != nil {
@@ -96,6 +146,18 @@ func main() {
 		return
 	}
 
+	// 显示支持的协议
+	if cli.ListProtocols {
+		if cli.Format == "json" {
+			if err := writeProtocolsJSON(os.Stdout, brute.GetProtocols()); err != nil {
+				gologger.Fatal().Msgf("Failed to list protocols: %v", err)
+			}
+			return
+		}
+		printProtocols(os.Stdout, brute.GetProtocols())
+		return
+	}
+
 	// 设置日志级别
 	if cli.Verbose {
 		gologger.DefaultLogger.SetMaxLevel(levels.LevelVerbose)
@@ -178,7 +240,7 @@ func executeBrute(ctx context.Context, cli *CLI) error {
 		for _, protocol := range protocols {
 			targetPorts := ports
 			if len(targetPorts) == 0 {
-				targetPorts = utils.GetDefaultPorts(protocol)
+				targetPorts = brute.GetDefaultPorts(protocol)
 			}
 
 			for _, port := range targetPorts {
@@ -191,9 +253,8 @@ func executeBrute(ctx context.Context, cli *CLI) error {
 		}
 	}
 
-	atomic.AddInt32(&totalCount, int32(len(bruteTargets)*len(usernames)*len(passwords)))
 	// 执行批量爆破
-	return brute.BatchBruteWithConfig(ctx, bruteTargets, usernames, passwords, resultCallback, bruteConfig)
+	return runBrute(ctx, cli, bruteTargets, usernames, passwords, resultCallback, bruteConfig)
 }
 
 // executeBruteWithServiceTargets 使用服务目标文件执行爆破
@@ -235,8 +296,15 @@ func executeBruteWithServiceTargets(ctx context.Context, cli *CLI) error {
 			continue
 		}
 
+		protocol, ok := brute.ResolveProtocol(serviceTarget.Protocol)
+		if !ok {
+			gologger.Warning().Msgf("Skipping service target with unsupported protocol %s://%s:%d",
+				serviceTarget.Protocol, serviceTarget.Host, serviceTarget.Port)
+			continue
+		}
+
 		bruteTargets = append(bruteTargets, brute.Target{
-			Type: serviceTarget.Protocol,
+			Type: protocol,
 			Host: serviceTarget.Host,
 			Port: serviceTarget.Port,
 		})
@@ -246,13 +314,81 @@ func executeBruteWithServiceTargets(ctx context.Context, cli *CLI) error {
 		return fmt.Errorf("no valid brute targets found from service target file")
 	}
 
-	gologger.Info().Msgf("Starting brute force on %d targets with %d usernames and %d passwords",
-		len(bruteTargets), len(usernames), len(passwords))
-
-	atomic.AddInt32(&totalCount, int32(len(bruteTargets)*len(usernames)*len(passwords)))
+	gologger.Info().Msgf("Starting brute force on %d targets", len(bruteTargets))
 
 	// 执行批量爆破
-	return brute.BatchBruteWithConfig(ctx, bruteTargets, usernames, passwords, resultCallback, bruteConfig)
+	return runBrute(ctx, cli, bruteTargets, usernames, passwords, resultCallback, bruteConfig)
+}
+
+// runBrute 构建引擎并执行爆破，总任务数由引擎根据字典统计
+func runBrute(ctx context.Context, cli *CLI, targets []brute.Target, usernames, passwords []string, callback brute.ResultCallback, config *brute.Config) error {
+	// 所有目标共享同一个喷洒域，例如同一 AD 域内的多台域控
+	if cli.Realm != "" {
+		for i := range targets {
+			targets[i].Realm = cli.Realm
+		}
+	}
+
+	// 选择内置凭据包
+	provider, err := selectPacks(cli)
+	if err != nil {
+		return err
+	}
+	config.CredentialProvider = provider
+
+	engine, err := brute.NewBuilder(ctx).
+		WithConfig(config).
+		WithTargets(targets).
+		WithUserDict(usernames).
+		WithPassDict(passwords).
+		WithResultCallback(callback).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	// 从标准输入读取控制命令，运行中暂停、恢复或调整速度
+	if cli.Interactive {
+		go runInteractive(ctx, engine, os.Stdin)
+	}
+
+	// 从检查点恢复进度
+	if cli.ResumeFile != "" {
+		if err := engine.Resume(cli.ResumeFile); err != nil {
+			return fmt.Errorf("failed to resume from checkpoint: %w", err)
+		}
+	}
+
+	total, _, _, _, _, _ := engine.GetProgressStats()
+	atomic.StoreInt64(&totalCount, total)
+
+	if err := engine.Start(); err != nil {
+		return err
+	}
+	reportTargetStats(cli, engine.GetTargetStats())
+
+	if ctx.Err() != nil && config.CheckpointFile != "" {
+		gologger.Info().Msgf("Run interrupted, continue with: -resume %s", config.CheckpointFile)
+	}
+	return nil
+}
+
+// reportTargetStats 输出每个目标的统计，JSON 格式下按需写入输出文件或标准输出
+func reportTargetStats(cli *CLI, stats []brute.TargetStats) {
+	if !cli.Silent {
+		printTargetStats(os.Stdout, stats)
+	}
+
+	if cli.Format != "json" || !cli.TargetStats {
+		return
+	}
+	var w io.Writer = os.Stdout
+	if outputFile != nil {
+		w = outputFile
+	}
+	if err := writeTargetStatsJSON(w, stats); err != nil {
+		gologger.Error().Msgf("Failed to write target stats: %v", err)
+	}
 }
 
 // parseTargets 解析目标
@@ -305,12 +441,13 @@ func parseProtocols(cli *CLI) ([]string, error) {
 		return nil, fmt.Errorf("no protocols specified")
 	}
 
-	// 验证协议
-	supportedProtocols := brute.GetSupportedProtocols()
-	for _, protocol := range protocols {
-		if !lo.Contains(supportedProtocols, protocol) {
+	// 验证协议，别名统一为协议名
+	for i, protocol := range protocols {
+		name, ok := brute.ResolveProtocol(protocol)
+		if !ok {
 			return nil, fmt.Errorf("unsupported protocol: %s", protocol)
 		}
+		protocols[i] = name
 	}
 
 	return lo.Uniq(protocols), nil
@@ -363,28 +500,13 @@ func parseCredentials(cli *CLI) ([]string, []string, error) {
 	}
 	usernames = append(usernames, []string(cli.Usernames)...)
 
-	if cli.UserFile != "" {
-		fileUsers, err := utils.LoadLinesFromFile(cli.UserFile)
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to load usernames: %w", err)
-		}
-		usernames = append(usernames, fileUsers...)
-	}
-
+	// 用户名文件和密码文件不在此处载入，而是交给引擎按需流式读取
 	// 解析密码
 	if cli.Password != "" {
 		passwords = append(passwords, cli.Password)
 	}
 	passwords = append(passwords, []string(cli.Passwords)...)
 
-	if cli.PassFile != "" {
-		filePasswords, err := utils.LoadLinesFromFile(cli.PassFile)
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to load passwords: %w", err)
-		}
-		passwords = append(passwords, filePasswords...)
-	}
-
 	// 解析用户名:密码文件
 	if cli.UserPassFile != "" {
 		lines, err := utils.LoadLinesFromFile(cli.UserPassFile)
@@ -413,6 +535,10 @@ func parseCredentials(cli *CLI) ([]string, []string, error) {
 // createBruteConfig 创建爆破配置
 func createBruteConfig(cli *CLI) *brute.Config {
 	config := brute.DefaultConfig()
+	// 设置字典文件，由引擎按需流式读取
+	config.UserDictFile = cli.UserFile
+	config.PassDictFile = cli.PassFile
+	config.RulesFile = cli.RulesFile
 	// 设置进度
 	if cli.ShowProgress {
 		config.ShowProgress = true
@@ -430,9 +556,39 @@ func createBruteConfig(cli *CLI) *brute.Config {
 
 	// 设置延迟
 	if cli.Delay != "" {
-		if delay, err := time.ParseDuration(cli.Delay); err == nil {
-			config.MinDelay = delay
-			config.MaxDelay = delay
+		if minDelay, maxDelay, err := parseDelay(cli.Delay); err == nil {
+			config.MinDelay = minDelay
+			config.MaxDelay = maxDelay
+		} else {
+			gologger.Warning().Msgf("Ignoring invalid delay %q: %v", cli.Delay, err)
+		}
+	}
+
+	// 设置自适应并发
+	config.Adaptive = !cli.NoAdaptive
+	if cli.AdaptiveMaxDelay != "" {
+		if delay, err := time.ParseDuration(cli.AdaptiveMaxDelay); err == nil {
+			config.AdaptiveMaxDelay = delay
+		}
+	}
+
+	// 设置分层限流
+	for _, layer := range []struct {
+		value string
+		limit *brute.RateLimit
+	}{
+		{cli.RateLimit, &config.GlobalRateLimit},
+		{cli.HostRateLimit, &config.HostRateLimit},
+		{cli.SubnetRateLimit, &config.SubnetRateLimit},
+		{cli.ProtocolRateLimit, &config.ProtocolRateLimit},
+	} {
+		if layer.value == "" {
+			continue
+		}
+		if limit, err := brute.ParseRateLimit(layer.value); err == nil {
+			*layer.limit = limit
+		} else {
+			gologger.Warning().Msgf("Ignoring invalid rate limit %q: %v", layer.value, err)
 		}
 	}
 
@@ -443,15 +599,65 @@ func createBruteConfig(cli *CLI) *brute.Config {
 		}
 	}
 
-	// 设置重试
-	if cli.Retries > 0 {
+	// 设置重试，-retries 0 表示不重试
+	if cli.Retries >= 0 {
 		config.MaxRetries = cli.Retries
 	}
-	// 如果都没有设置，保持默认值
+
+	// 设置会话复用
+	config.SessionReuse = cli.ReuseSessions
+	config.MaxSessionAttempts = cli.SessionAttempts
 
 	// 设置停止条件
 	config.OkToStop = cli.OkToStop
 
+	// 设置成功结果的随机密码验证
+	config.VerifySuccess = !cli.NoVerify
+
+	// 设置未授权访问探测
+	config.UnauthProbe = !cli.NoUnauth
+
+	// 设置熔断阈值，0 表示不放弃目标
+	if cli.FinishThreshold >= 0 {
+		config.FinishingThreshold = cli.FinishThreshold
+	}
+
+	// 设置存活探测
+	config.PreCheck = !cli.NoPreCheck
+	if cli.PreCheckTimeout != "" {
+		if timeout, err := time.ParseDuration(cli.PreCheckTimeout); err == nil {
+			config.PreCheckTimeout = timeout
+		}
+	}
+
+	// 设置目标调度
+	if cli.TargetOrder != "" {
+		config.TargetOrder = brute.TargetOrder(cli.TargetOrder)
+	}
+	if cli.MaxActiveTargets > 0 {
+		config.MaxActiveTargets = cli.MaxActiveTargets
+	}
+	config.ShuffleSeed = int64(cli.ShuffleSeed)
+
+	// 设置调度策略
+	if cli.Strategy != "" {
+		config.Strategy = brute.Strategy(cli.Strategy)
+	}
+	if cli.SprayWindow != "" {
+		if window, err := time.ParseDuration(cli.SprayWindow); err == nil {
+			config.SprayWindow = window
+		}
+	}
+	if cli.SprayPerRound > 0 {
+		config.SprayPerRound = cli.SprayPerRound
+	}
+
+	// 设置指标导出
+	config.MetricsListen = cli.MetricsListen
+
+	// 设置检查点文件
+	config.CheckpointFile = cli.CheckpointFile
+
 	// 设置跳过空值选项
 	// 如果用户明确允许空凭据，则不跳过它们
 	if cli.AllowBlankUsername {
@@ -461,14 +667,24 @@ func createBruteConfig(cli *CLI) *brute.Config {
 	if cli.AllowBlankPassword {
 		config.SkipEmptyPassword = false
 	}
+
+	// 只需要密码的协议（如 redis、vnc、snmp）无论是否指定都只尝试密码
+	config.OnlyNeedPassword = cli.PasswordOnly
+
+	// 认证成功后采集附加信息
+	config.Enrich = cli.Enrich
 	return config
 }
 
 // createResultCallback 创建结果回调
 func createResultCallback(cli *CLI) brute.ResultCallback {
-	var outputFile *os.File
 	if cli.Output != "" {
-		file, err := os.Create(cli.Output)
+		// 从检查点恢复时追加写入，保留中断前已写入的凭据，恢复的凭据不会再次回调
+		flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if cli.ResumeFile != "" {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+		file, err := os.OpenFile(cli.Output, flags, 0644)
 		if err != nil {
 			gologger.Error().Msgf("Failed to create output file: %v", err)
 		} else {
@@ -486,7 +702,7 @@ func createResultCallback(cli *CLI) brute.ResultCallback {
 				data, _ := json.Marshal(result)
 				gologger.Info().Msgf("成功结果: %s", string(data))
 			default:
-				gologger.Info().Msgf("%s", result.String())
+				gologger.Info().Msgf("%s%s", result.String(), formatExtraInfo(result.ExtraInfo))
 			}
 
 			// 写入文件
@@ -496,7 +712,7 @@ func createResultCallback(cli *CLI) brute.ResultCallback {
 					data, _ := json.Marshal(result)
 					outputFile.WriteString(string(data) + "\n")
 				default:
-					outputFile.WriteString(fmt.Sprintf("[SUCCESS] %s\n", result.String()))
+					outputFile.WriteString(fmt.Sprintf("[SUCCESS] %s%s\n", result.String(), formatExtraInfo(result.ExtraInfo)))
 				}
 			}
 		} else {
@@ -509,7 +725,7 @@ func createResultCallback(cli *CLI) brute.ResultCallback {
 					data, _ := json.Marshal(result)
 					fmt.Println(string(data))
 				default:
-					fmt.Printf("[FAILED] %s\n", result.String())
+					fmt.Printf("[FAILED] %s (%s)\n", result.String(), result.FailureKind)
 				}
 			}
 		}
@@ -522,7 +738,7 @@ func parseFlags() (*CLI, error) {
 		TargetConcurrent: 0,  // 使用0作为默认值，表示未设置
 		TaskConcurrent:   0,  // 使用0作为默认值，表示未设置
 		Timeout:          "", // 使用空字符串作为默认值，表示未设置
-		Retries:          0,  // 使用0作为默认值，表示未设置
+		Retries:          3,  // 默认重试3次
 		Format:           "text",
 	}
 
@@ -535,7 +751,7 @@ func parseFlags() (*CLI, error) {
 		flagSet.IntVar(&cli.Port, "port", 0, "Target port"),
 		flagSet.StringVar(&cli.Ports, "ports", "", "Port range (e.g. 22,3389,1433-1434)"),
 		flagSet.StringVar(&cli.PortFile, "port-file", "", "File containing ports"),
-		flagSet.StringVar(&cli.Protocol, "protocol", "", "Protocol to use (ssh,mysql,ftp,etc.)"),
+		flagSet.StringVar(&cli.Protocol, "protocol", "", fmt.Sprintf("Protocol to use (%s)", strings.Join(brute.GetSupportedProtocols(), ","))),
 		flagSet.StringSliceVar(&cli.Protocols, "protocols", []string{}, "Protocols to use (comma separated)", goflags.NormalizedStringSliceOptions),
 	)
 
@@ -546,18 +762,55 @@ func parseFlags() (*CLI, error) {
 		flagSet.StringVarP(&cli.Password, "password", "p", "", "Password for authentication"),
 		flagSet.StringSliceVar(&cli.Passwords, "passwords", []string{}, "Passwords (comma separated)", goflags.NormalizedStringSliceOptions),
 		flagSet.StringVarP(&cli.PassFile, "pass-file", "pf", "", "File containing passwords"),
+		flagSet.StringVar(&cli.RulesFile, "rules", "", "File containing hashcat-style rules applied to every password (e.g. c$2$0$2$4)"),
 		flagSet.StringVar(&cli.UserPassFile, "userpass-file", "", "File containing username:password combinations"),
+		flagSet.StringSliceVar(&cli.Packs, "pack", []string{}, "Built-in credential packs to use (comma separated, see 'x-crack packs list'); packs matching each protocol are used when no dictionary is given", goflags.NormalizedStringSliceOptions),
 		flagSet.BoolVar(&cli.AllowBlankUsername, "allow-blank-username", false, "Allow blank/empty usernames during brute force"),
 		flagSet.BoolVar(&cli.AllowBlankPassword, "allow-blank-password", false, "Allow blank/empty passwords during brute force"),
+		flagSet.BoolVar(&cli.PasswordOnly, "password-only", false, "Ignore usernames and try each password once for every protocol (redis, vnc and snmp always do)"),
 	)
 
 	flagSet.CreateGroup("brute", "Brute force settings",
 		flagSet.IntVar(&cli.TargetConcurrent, "target-concurrent", 10, "Number of concurrent targets"),
 		flagSet.IntVar(&cli.TaskConcurrent, "task-concurrent", 10, "Number of concurrent tasks per target"),
-		flagSet.StringVar(&cli.Delay, "delay", "", "Delay between requests (e.g. 100ms)"),
+		flagSet.StringVar(&cli.Delay, "delay", "", "Delay between requests to the same target, a range is randomized (e.g. 100ms, 200ms-1s)"),
 		flagSet.StringVar(&cli.Timeout, "timeout", "10s", "Timeout for each request"),
-		flagSet.IntVar(&cli.Retries, "retries", 3, "Number of retries for failed requests"),
+		flagSet.IntVar(&cli.Retries, "retries", 3, "Number of retries for transient network failures (timeouts, resets)"),
 		flagSet.BoolVarP(&cli.OkToStop, "ok-to-stop", "ots", false, "Stop after first successful authentication"),
+		flagSet.BoolVar(&cli.NoUnauth, "no-unauth", false, "Disable the anonymous/no-auth access probe that runs before credentials (redis, mongodb, ftp, vnc, snmp, socks5, http_proxy, memcached, zookeeper)"),
+		flagSet.BoolVar(&cli.NoVerify, "no-verify", false, "Disable the random password check that flags targets accepting any password (honeypots)"),
+		flagSet.IntVar(&cli.FinishThreshold, "finishing-threshold", 10, "Abandon a target after this many consecutive network failures (0 to disable)"),
+		flagSet.BoolVar(&cli.NoPreCheck, "no-precheck", false, "Disable the reachability probe before brute forcing each target"),
+		flagSet.StringVar(&cli.PreCheckTimeout, "precheck-timeout", "3s", "Timeout for the reachability probe"),
+		flagSet.BoolVar(&cli.NoAdaptive, "no-adaptive", false, "Disable adaptive per-target concurrency that backs off on timeouts and refusals"),
+		flagSet.StringVar(&cli.AdaptiveMaxDelay, "adaptive-max-delay", "5s", "Maximum extra delay added by adaptive concurrency"),
+		flagSet.StringSliceVar(&cli.ReuseSessions, "reuse-sessions", []string{}, "Protocols that try consecutive credentials over one connection (ssh,ftp,smtp,pop3,imap or all)", goflags.NormalizedStringSliceOptions),
+		flagSet.IntVar(&cli.SessionAttempts, "session-attempts", 0, "Maximum credentials tried over one reused session (0 until the server disconnects)"),
+	)
+
+	flagSet.CreateGroup("ratelimit", "Rate limit settings (rate[/burst] requests per second)",
+		flagSet.StringVar(&cli.RateLimit, "rate-limit", "", "Global rate limit (e.g. 50/100)"),
+		flagSet.StringVar(&cli.HostRateLimit, "host-rate-limit", "", "Rate limit per host across all protocols"),
+		flagSet.StringVar(&cli.SubnetRateLimit, "subnet-rate-limit", "", "Rate limit per /24 subnet (/64 for IPv6)"),
+		flagSet.StringVar(&cli.ProtocolRateLimit, "protocol-rate-limit", "", "Rate limit per protocol"),
+	)
+
+	flagSet.CreateGroup("scheduling", "Target scheduling settings",
+		flagSet.StringVar(&cli.TargetOrder, "target-order", "input", "Order in which targets are admitted (input,shuffle,subnet)"),
+		flagSet.IntVar(&cli.MaxActiveTargets, "max-active-targets", 0, "Maximum number of targets processed at once, queued targets use no goroutine (default: target-concurrent)"),
+		flagSet.IntVar(&cli.ShuffleSeed, "shuffle-seed", 0, "Seed for -target-order shuffle to reproduce an order (0 for random)"),
+	)
+
+	flagSet.CreateGroup("spray", "Password spraying settings",
+		flagSet.StringVar(&cli.Strategy, "strategy", "standard", "Credential scheduling strategy (standard,spray)"),
+		flagSet.StringVar(&cli.SprayWindow, "spray-window", "30m", "Observation window between spray rounds, should exceed the lockout observation window"),
+		flagSet.IntVar(&cli.SprayPerRound, "spray-per-round", 1, "Number of passwords tried against every user in each spray round"),
+		flagSet.StringVar(&cli.Realm, "realm", "", "Realm shared by all targets, spray rounds are synchronized within a realm (default: per host)"),
+	)
+
+	flagSet.CreateGroup("resume", "Checkpoint and resume settings",
+		flagSet.StringVar(&cli.CheckpointFile, "checkpoint", "", "Periodically save progress to this checkpoint file"),
+		flagSet.StringVar(&cli.ResumeFile, "resume", "", "Resume an interrupted run from checkpoint file (use the same targets and dictionaries)"),
 	)
 
 	flagSet.CreateGroup("output", "Output settings",
@@ -569,11 +822,16 @@ func parseFlags() (*CLI, error) {
 		flagSet.BoolVar(&cli.NoColor, "no-color", false, "Disable colored output"),
 		flagSet.BoolVar(&cli.ShowFailed, "show-failed", false, "Show failed authentication attempts"),
 		flagSet.BoolVarP(&cli.ShowProgress, "show-progress", "sp", false, "Show progress bar during brute force"),
+		flagSet.StringVar(&cli.MetricsListen, "metrics-listen", "", "Serve Prometheus metrics on this address (e.g. 127.0.0.1:9090)"),
+		flagSet.BoolVar(&cli.Interactive, "interactive", false, "Read control commands from stdin (pause, resume, concurrency, delay, rate, status)"),
+		flagSet.BoolVar(&cli.TargetStats, "target-stats", false, "Include per-target statistics in json output"),
+		flagSet.BoolVar(&cli.Enrich, "enrich", false, "Collect read-only details (version, privileges, system info) after a successful login"),
 	)
 
 	flagSet.CreateGroup("misc", "Miscellaneous settings",
 		flagSet.StringVar(&cli.ConfigFile, "config", defaultConfigLocation, "Configuration file path"),
 		flagSet.BoolVar(&cli.Version, "version", false, "Show version information"),
+		flagSet.BoolVar(&cli.ListProtocols, "list-protocols", false, "List supported protocols with their aliases and default ports"),
 	)
 	// 其他设置
 	if err := flagSet.Parse(); err != nil {
@@ -592,6 +850,27 @@ func (c *CLI) loadConfigFrom(location string) error {
 	return fileutil.Unmarshal(fileutil.YAML, []byte(location), c)
 }
 
+// parseDelay 解析延迟，支持 100ms 形式的固定延迟和 200ms-1s 形式的随机范围
+func parseDelay(value string) (time.Duration, time.Duration, error) {
+	minValue, maxValue, isRange := strings.Cut(value, "-")
+	minDelay, err := time.ParseDuration(strings.TrimSpace(minValue))
+	if err != nil {
+		return 0, 0, err
+	}
+	if !isRange {
+		return minDelay, minDelay, nil
+	}
+
+	maxDelay, err := time.ParseDuration(strings.TrimSpace(maxValue))
+	if err != nil {
+		return 0, 0, err
+	}
+	if minDelay > maxDelay {
+		return 0, 0, fmt.Errorf("min delay %v is greater than max delay %v", minDelay, maxDelay)
+	}
+	return minDelay, maxDelay, nil
+}
+
 // validateCLI 验证命令行参数
 func validateCLI(cli *CLI) error {
 	if cli.Target == "" && len(cli.Targets) == 0 && cli.TargetFile == "" && cli.ServiceTarget == "" {
@@ -603,6 +882,14 @@ func validateCLI(cli *CLI) error {
 		return fmt.Errorf("no protocols specified")
 	}
 
+	if cli.Strategy != "" && !lo.Contains(brute.Strategies(), brute.Strategy(cli.Strategy)) {
+		return fmt.Errorf("unknown strategy: %s", cli.Strategy)
+	}
+
+	if cli.TargetOrder != "" && !lo.Contains(brute.TargetOrders(), brute.TargetOrder(cli.TargetOrder)) {
+		return fmt.Errorf("unknown target order: %s", cli.TargetOrder)
+	}
+
 	return nil
 }
 
@@ -620,7 +907,7 @@ func setupSignalHandler(cancel context.CancelFunc) {
 
 // showStatistics 显示统计信息
 func showStatistics() {
-	total := atomic.LoadInt32(&totalCount)
+	total := atomic.LoadInt64(&totalCount)
 	success := atomic.LoadInt32(&successCount)
 	failure := atomic.LoadInt32(&failureCount)
 
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/packs"
+	"github.com/projectdiscovery/gologger"
+	"github.com/samber/lo"
+)
+
+// packsUsage 凭据包子命令的用法
+const packsUsage = `usage:
+  x-crack packs list [-json]                       list built-in credential packs
+  x-crack packs export [-json] <pack|protocol>...  export credentials as user:pass lines`
+
+// runPacks 执行凭据包子命令，列出或导出内置凭据包
+func runPacks(w io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errors.New(packsUsage)
+	}
+
+	flags := flag.NewFlagSet("packs "+args[0], flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "Write packs as JSON lines")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		if *asJSON {
+			return writePacksJSON(w, packs.List())
+		}
+		printPacks(w, packs.List())
+		return nil
+	case "export":
+		if flags.NArg() == 0 {
+			return errors.New("export needs at least one pack name or protocol")
+		}
+		selected, err := resolvePacks(flags.Args())
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return writePacksJSON(w, selected)
+		}
+		for _, credential := range packs.Merge(selected) {
+			fmt.Fprintf(w, "%s:%s\n", credential.Username, credential.Password)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown packs command %q\n%s", args[0], packsUsage)
+	}
+}
+
+// resolvePacks 按名称或协议查找凭据包，协议对应该协议的所有凭据包
+func resolvePacks(names []string) ([]*packs.Pack, error) {
+	var selected []*packs.Pack
+	for _, name := range names {
+		if pack, ok := packs.Get(name); ok {
+			selected = append(selected, pack)
+			continue
+		}
+		matched := packs.ForProtocol(name)
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("no credential pack or protocol named %q", name)
+		}
+		selected = append(selected, matched...)
+	}
+	return lo.Uniq(selected), nil
+}
+
+// selectPacks 选择爆破使用的内置凭据包
+// 指定了凭据包时所有目标都使用这些凭据，未提供任何字典时按目标协议自动选择
+func selectPacks(cli *CLI) (brute.CredentialProvider, error) {
+	if len(cli.Packs) > 0 {
+		selected, err := resolvePacks(cli.Packs)
+		if err != nil {
+			return nil, err
+		}
+		credentials := packs.Merge(selected)
+		gologger.Info().Msgf("Using credential packs %s (%d credentials)",
+			strings.Join(lo.Map(selected, func(pack *packs.Pack, _ int) string { return pack.Name }), ","), len(credentials))
+		return func(string) []brute.Credential {
+			return credentials
+		}, nil
+	}
+
+	if hasDictionary(cli) {
+		return nil, nil
+	}
+	gologger.Info().Msg("No dictionary given, using built-in credential packs for each protocol")
+	return packs.Credentials, nil
+}
+
+// hasDictionary 返回是否通过参数或文件提供了用户名或密码
+func hasDictionary(cli *CLI) bool {
+	return cli.Username != "" || len(cli.Usernames) > 0 || cli.UserFile != "" ||
+		cli.Password != "" || len(cli.Passwords) > 0 || cli.PassFile != "" ||
+		cli.UserPassFile != ""
+}
+
+// printPacks 以表格形式输出凭据包
+func printPacks(w io.Writer, list []*packs.Pack) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "PACK\tPRODUCT\tPROTOCOLS\tSUPPORTED\tCREDENTIALS")
+	for _, pack := range list {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%d\n",
+			pack.Name, pack.Product, formatList(pack.Protocols), formatList(pack.Supported()), len(pack.Credentials))
+	}
+	table.Flush()
+}
+
+// writePacksJSON 将凭据包逐行写出为 JSON
+func writePacksJSON(w io.Writer, list []*packs.Pack) error {
+	encoder := json.NewEncoder(w)
+	for _, pack := range list {
+		if err := encoder.Encode(pack); err != nil {
+			return fmt.Errorf("failed to encode pack: %w", err)
+		}
+	}
+	return nil
+}
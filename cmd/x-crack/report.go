@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/samber/lo"
+)
+
+// printTargetStats 以表格形式输出每个目标的统计
+func printTargetStats(w io.Writer, stats []brute.TargetStats) {
+	if len(stats) == 0 {
+		return
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TARGET\tATTEMPTS\tSUCCESS\tFAILED\tRETRIES\tSKIPPED\tAVG\tP95\tDURATION\tFAILURES\tSTATUS")
+	for _, s := range stats {
+		status := "done"
+		switch {
+		case s.Unauthenticated:
+			status = "unauthenticated"
+		case s.Abandoned != "":
+			status = "abandoned: " + s.Abandoned
+		}
+		fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%d\t%d\t%v\t%v\t%v\t%s\t%s\n",
+			s.Target, s.Attempts, s.Successes, s.Failed(), s.Retries, s.Skipped,
+			s.AvgLatency.Round(time.Millisecond), s.P95Latency.Round(time.Millisecond),
+			s.LastAttempt.Sub(s.FirstAttempt).Round(time.Second), formatFailures(s.Failures), status)
+	}
+	table.Flush()
+}
+
+// formatFailures 按失败类型的固定顺序格式化失败次数，例如 auth=10,timeout=2
+func formatFailures(failures map[brute.FailureKind]int64) string {
+	var parts []string
+	for _, kind := range brute.FailureKinds() {
+		if count := failures[kind]; count > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", kind, count))
+		}
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ",")
+}
+
+// writeTargetStatsJSON 将每个目标的统计逐行写出为 JSON
+func writeTargetStatsJSON(w io.Writer, stats []brute.TargetStats) error {
+	encoder := json.NewEncoder(w)
+	for _, s := range stats {
+		if err := encoder.Encode(s); err != nil {
+			return fmt.Errorf("failed to encode target stats: %w", err)
+		}
+	}
+	return nil
+}
+
+// printProtocols 以表格形式输出支持的协议
+func printProtocols(w io.Writer, protocols []brute.ProtocolInfo) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "PROTOCOL\tALIASES\tPORTS\tTRANSPORT\tAUTH\tTLS\tSESSION\tUNAUTH")
+	for _, info := range protocols {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%v\t%v\t%v\n",
+			info.Name, formatList(info.Aliases), formatList(lo.Map(info.DefaultPorts, func(port int, _ int) string { return strconv.Itoa(port) })),
+			info.Transport, info.AuthModel, info.TLS, brute.SupportsSessionReuse(info.Name), brute.SupportsUnauthProbe(info.Name))
+	}
+	table.Flush()
+}
+
+// formatList 以逗号连接列表，空列表输出 -
+func formatList(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ",")
+}
+
+// writeProtocolsJSON 将支持的协议逐行写出为 JSON
+func writeProtocolsJSON(w io.Writer, protocols []brute.ProtocolInfo) error {
+	encoder := json.NewEncoder(w)
+	for _, info := range protocols {
+		if err := encoder.Encode(info); err != nil {
+			return fmt.Errorf("failed to encode protocol: %w", err)
+		}
+	}
+	return nil
+}
+
+// formatExtraInfo 以 key=value 形式输出认证成功后采集的附加信息，按 key 排序
+func formatExtraInfo(info map[string]interface{}) string {
+	if len(info) == 0 {
+		return ""
+	}
+	var builder strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(info)) {
+		value := info[key]
+		if values, ok := value.([]string); ok {
+			value = strings.Join(values, "; ")
+		}
+		fmt.Fprintf(&builder, " %s=%q", key, fmt.Sprint(value))
+	}
+	return builder.String()
+}
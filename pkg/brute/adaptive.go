@@ -0,0 +1,111 @@
+package brute
+
+import (
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// adaptiveDelayStep 自适应延迟的调整步长
+const adaptiveDelayStep = 100 * time.Millisecond
+
+// adaptiveController 目标级别的 AIMD 并发控制器
+// 每观察 AdaptiveWindow 次尝试评估一次：超时、拒绝连接和连接重置的比例超过阈值时并发数减半、附加延迟加倍，
+// 否则并发数加一、附加延迟减少一个步长，调整范围为 [AdaptiveMinConcurrent, 当前单目标并发数] 和 [0, AdaptiveMaxDelay]
+type adaptiveController struct {
+	mutex       sync.Mutex
+	concurrency int           // 当前并发数
+	delay       time.Duration // 当前附加延迟
+	samples     int           // 本轮观察的尝试次数
+	overloads   int           // 本轮观察到的过载失败次数
+}
+
+// newAdaptiveController 创建控制器，初始为满并发、无附加延迟
+func newAdaptiveController(concurrency int) *adaptiveController {
+	return &adaptiveController{concurrency: concurrency}
+}
+
+// extraDelay 返回当前的附加延迟，未启用自适应时为 0
+func (c *adaptiveController) extraDelay() time.Duration {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.delay
+}
+
+// isOverload 失败类型是否表明目标已经不堪重负
+func isOverload(kind FailureKind) bool {
+	return kind == FailureTimeout || kind == FailureConnRefused || kind == FailureConnReset
+}
+
+// adjustConcurrency 记录一次尝试的结果，并在观察窗口结束时调整目标的并发数和延迟
+func (e *Engine) adjustConcurrency(process *targetProcess, kind FailureKind) {
+	c := process.adaptive
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.samples++
+	if isOverload(kind) {
+		c.overloads++
+	}
+	if c.samples < e.config.AdaptiveWindow {
+		return
+	}
+
+	ratio := float64(c.overloads) / float64(c.samples)
+	c.samples, c.overloads = 0, 0
+
+	concurrency, delay := c.concurrency, c.delay
+	if ratio > e.config.AdaptiveThreshold {
+		// 乘性减少
+		concurrency = max(concurrency/2, min(e.config.AdaptiveMinConcurrent, e.getTaskConcurrent()))
+		delay = min(max(delay*2, adaptiveDelayStep), e.config.AdaptiveMaxDelay)
+	} else {
+		// 加性增加
+		concurrency = min(concurrency+1, e.getTaskConcurrent())
+		delay = max(delay-adaptiveDelayStep, 0)
+	}
+	if concurrency == c.concurrency && delay == c.delay {
+		return
+	}
+
+	if ratio > e.config.AdaptiveThreshold {
+		gologger.Debug().Msgf("Target %s overloaded (%.0f%% timeouts/refusals), concurrency %d -> %d, delay %v -> %v",
+			process.Target, ratio*100, c.concurrency, concurrency, c.delay, delay)
+	} else {
+		gologger.Debug().Msgf("Target %s recovering, concurrency %d -> %d, delay %v -> %v",
+			process.Target, c.concurrency, concurrency, c.delay, delay)
+	}
+	c.concurrency, c.delay = concurrency, delay
+	process.semaphore.resize(concurrency)
+}
+
+// TargetConcurrency 目标当前的并发状态
+type TargetConcurrency struct {
+	Used  int           `json:"used"`  // 正在执行的任务数
+	Limit int           `json:"limit"` // 当前允许的并发数
+	Delay time.Duration `json:"delay"` // 自适应控制附加的请求延迟
+}
+
+// GetTargetConcurrency 获取每个目标当前的并发数和自适应延迟
+func (e *Engine) GetTargetConcurrency() map[string]TargetConcurrency {
+	status := make(map[string]TargetConcurrency)
+	e.processes.Range(func(key, value interface{}) bool {
+		process := value.(*targetProcess)
+		used, limit := process.semaphore.status()
+		status[key.(string)] = TargetConcurrency{
+			Used:  used,
+			Limit: limit,
+			Delay: process.adaptive.extraDelay(),
+		}
+		return true
+	})
+	return status
+}
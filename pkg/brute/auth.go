@@ -0,0 +1,71 @@
+package brute
+
+import (
+	"fmt"
+
+	"github.com/samber/lo"
+)
+
+// AuthModel 协议的认证模型，决定为目标生成哪些用户名和密码组合
+type AuthModel int
+
+const (
+	AuthUserPassword     AuthModel = iota // 需要用户名和密码，尝试 用户名×密码
+	AuthPasswordOnly                      // 只需要密码，例如 Redis、VNC 和 SNMP community，每个密码只尝试一次
+	AuthUsernameOptional                  // 用户名可以为空，例如只提示输入密码的 Telnet 设备，额外尝试空用户名
+	AuthNone                              // 没有可以爆破的认证，例如 ZooKeeper，只进行未授权访问探测
+)
+
+// authModelNames 认证模型的名称，用于日志和协议列表
+var authModelNames = map[AuthModel]string{
+	AuthUserPassword:     "user_password",
+	AuthPasswordOnly:     "password_only",
+	AuthUsernameOptional: "username_optional",
+	AuthNone:             "none",
+}
+
+// String 返回认证模型的名称
+func (m AuthModel) String() string {
+	if name, ok := authModelNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("auth_model(%d)", int(m))
+}
+
+// MarshalText 以名称序列化认证模型
+func (m AuthModel) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// GetAuthModel 获取协议的认证模型，未注册的协议需要用户名和密码
+func GetAuthModel(protocol string) AuthModel {
+	if handler, exists := GetProtocol(protocol); exists {
+		return handler.Info().AuthModel
+	}
+	return AuthUserPassword
+}
+
+// authModel 返回目标实际使用的认证模型，OnlyNeedPassword 时所有协议都只尝试密码
+func (c *Config) authModel(protocol string) AuthModel {
+	model := GetAuthModel(protocol)
+	if c.OnlyNeedPassword && model != AuthNone {
+		return AuthPasswordOnly
+	}
+	return model
+}
+
+// userWordlistFor 返回认证模型对应的用户名字典
+func (c *Config) userWordlistFor(model AuthModel) Wordlist {
+	switch model {
+	case AuthNone:
+		return SliceWordlist{}
+	case AuthPasswordOnly:
+		return SliceWordlist{""}
+	case AuthUsernameOptional:
+		// 空用户名只尝试一次，放在最前面
+		words := append([]string{""}, lo.Without(c.UserDict, "")...)
+		return buildWordlist(words, c.UserDictFile, false)
+	default:
+		return c.userWordlist()
+	}
+}
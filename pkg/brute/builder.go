@@ -4,29 +4,8 @@ import (
 	"context"
 	"fmt"
 	"time"
-
-	"github.com/samber/lo"
 )
 
-// protocolHandlers 协议处理器映射
-var protocolHandlers = map[string]BruteCallback{}
-
-// GetProtocolHandler 获取协议处理器
-func GetProtocolHandler(protocol string) (BruteCallback, bool) {
-	handler, exists := protocolHandlers[protocol]
-	return handler, exists
-}
-
-// RegisterProtocolHandler 注册协议处理器
-func RegisterProtocolHandler(protocol string, handler BruteCallback) {
-	protocolHandlers[protocol] = handler
-}
-
-// GetSupportedProtocols 获取支持的协议列表
-func GetSupportedProtocols() []string {
-	return lo.Keys(protocolHandlers)
-}
-
 // Builder 爆破引擎构建器
 type Builder struct {
 	config   *Config
@@ -42,6 +21,8 @@ type Target struct {
 	Type string `json:"type"`
 	Host string `json:"host"`
 	Port int    `json:"port"`
+	// Realm 共享账号锁定策略的目标使用同一个域，密码喷洒时同步推进轮次，为空时使用 Host
+	Realm string `json:"realm,omitempty"`
 }
 
 // NewBuilder 创建新的构建器
@@ -101,6 +82,18 @@ func (b *Builder) WithPassDictFile(filename string) *Builder {
 	return b
 }
 
+// WithRules 设置 hashcat 风格的密码变形规则
+func (b *Builder) WithRules(rules []string) *Builder {
+	b.config.Rules = rules
+	return b
+}
+
+// WithRulesFile 设置密码变形规则文件
+func (b *Builder) WithRulesFile(filename string) *Builder {
+	b.config.RulesFile = filename
+	return b
+}
+
 // WithResultCallback 设置结果回调
 func (b *Builder) WithResultCallback(callback ResultCallback) *Builder {
 	b.callback = callback
@@ -127,12 +120,43 @@ func (b *Builder) WithDelay(min, max time.Duration) *Builder {
 	return b
 }
 
+// WithRateLimits 设置全局、每个主机、每个子网和每个协议的分层限流
+func (b *Builder) WithRateLimits(global, host, subnet, protocol RateLimit) *Builder {
+	b.config.GlobalRateLimit = global
+	b.config.HostRateLimit = host
+	b.config.SubnetRateLimit = subnet
+	b.config.ProtocolRateLimit = protocol
+	return b
+}
+
 // WithRetries 设置重试次数
 func (b *Builder) WithRetries(retries int) *Builder {
 	b.config.MaxRetries = retries
 	return b
 }
 
+// WithPreCheck 设置存活探测
+func (b *Builder) WithPreCheck(enabled bool, timeout time.Duration) *Builder {
+	b.config.PreCheck = enabled
+	b.config.PreCheckTimeout = timeout
+	return b
+}
+
+// WithSpray 设置密码喷洒策略，每轮尝试 perRound 个密码，轮次之间等待 window
+func (b *Builder) WithSpray(window time.Duration, perRound int) *Builder {
+	b.config.Strategy = StrategySpray
+	b.config.SprayWindow = window
+	b.config.SprayPerRound = perRound
+	return b
+}
+
+// WithTargetOrder 设置目标的调度顺序和同时处理的目标数上限，maxActive 为 0 时与目标并发数相同
+func (b *Builder) WithTargetOrder(order TargetOrder, maxActive int) *Builder {
+	b.config.TargetOrder = order
+	b.config.MaxActiveTargets = maxActive
+	return b
+}
+
 // WithOkToStop 设置成功后停止
 func (b *Builder) WithOkToStop(okToStop bool) *Builder {
 	b.config.OkToStop = okToStop
@@ -145,6 +169,12 @@ func (b *Builder) WithFinishingThreshold(threshold int) *Builder {
 	return b
 }
 
+// WithCredentialProvider 设置按协议提供成对凭据的函数，例如内置的默认凭据包
+func (b *Builder) WithCredentialProvider(provider CredentialProvider) *Builder {
+	b.config.CredentialProvider = provider
+	return b
+}
+
 // WithCustomCallback 设置自定义回调
 func (b *Builder) WithCustomCallback(callback BruteCallback) *Builder {
 	b.config.CustomCallback = callback
@@ -170,7 +200,9 @@ func (b *Builder) Build() (*Engine, error) {
 
 	// 添加目标
 	for _, target := range b.targets {
-		engine.AddTarget(target.Type, target.Host, target.Port)
+		if err := engine.AddTargetInRealm(target.Type, target.Host, target.Port, target.Realm); err != nil {
+			return nil, err
+		}
 	}
 
 	// 生成爆破任务
@@ -181,39 +213,59 @@ func (b *Builder) Build() (*Engine, error) {
 	return engine, nil
 }
 
-// generateBruteItems 生成爆破任务
+// generateBruteItems 为每个目标生成惰性任务源
+// 任务项在引擎有空闲工作槽时才会生成，字典文件按需流式读取
+// 用户名按协议的认证模型选取，只需要密码的协议每个密码只生成一个任务
+// 设置了凭据提供函数时，有成对凭据的协议只尝试这些凭据
 func (b *Builder) generateBruteItems(engine *Engine) error {
-	for _, target := range b.targets {
-		for _, username := range b.config.UserDict {
-			// 跳过空用户名
-			if b.config.SkipEmptyUsername && username == "" {
-				continue
-			}
+	passwords := b.config.passWordlist()
+	// 同一认证模型的目标共享用户名字典，字典文件只统计一次条目数
+	userLists := make(map[AuthModel]Wordlist)
 
-			for _, password := range b.config.PassDict {
-				// 跳过空密码
-				if b.config.SkipEmptyPassword && password == "" {
-					continue
-				}
+	for _, target := range b.targets {
+		model := b.config.authModel(target.Type)
+		users, ok := userLists[model]
+		if !ok {
+			users = b.config.userWordlistFor(model)
+			userLists[model] = users
+		}
 
-				item := &BruteItem{
-					AllowBlankUsername: b.config.AllowBlankUsername,
-					AllowBlankPassword: b.config.AllowBlankPassword,
-					Type:               target.Type,
-					Target:             target.Host,
-					Port:               target.Port,
-					Username:           username,
-					Password:           password,
-					Context:            b.ctx,
-					Timeout:            b.config.Timeout,
-					Extra:              make(map[string]string),
-				}
+		template := BruteItem{
+			AllowBlankUsername: b.config.AllowBlankUsername,
+			AllowBlankPassword: b.config.AllowBlankPassword,
+			Type:               target.Type,
+			Target:             target.Host,
+			Port:               target.Port,
+			Context:            b.ctx,
+			Timeout:            b.config.Timeout,
+			Enrich:             b.config.Enrich,
+		}
 
-				if err := engine.Feed(item); err != nil {
-					return fmt.Errorf("failed to feed brute item: %w", err)
+		// 提供了成对凭据的协议按凭据逐组尝试
+		if b.config.CredentialProvider != nil {
+			if pairs := b.config.CredentialProvider(target.Type); len(pairs) > 0 {
+				source := b.config.newPairSource(template, model, pairs)
+				if err := engine.FeedSource(target.Type, target.Host, target.Port, source); err != nil {
+					return fmt.Errorf("failed to feed brute source: %w", err)
 				}
+				continue
 			}
 		}
+
+		var source *credentialSource
+		var err error
+		if b.config.Strategy == StrategySpray {
+			source, err = newSpraySource(template, users, passwords, b.config.SprayPerRound)
+		} else {
+			source, err = newCredentialSource(template, users, passwords)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create brute source: %w", err)
+		}
+
+		if err := engine.FeedSource(target.Type, target.Host, target.Port, source); err != nil {
+			return fmt.Errorf("failed to feed brute source: %w", err)
+		}
 	}
 
 	return nil
@@ -0,0 +1,294 @@
+package brute
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// checkpointVersion 检查点文件格式版本
+const checkpointVersion = 1
+
+// Credential 用户名密码对
+type Credential struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Checkpoint 爆破进度检查点，用于中断后断点续传
+type Checkpoint struct {
+	Version   int                          `json:"version"`
+	UpdatedAt time.Time                    `json:"updated_at"`
+	Targets   map[string]*TargetCheckpoint `json:"targets"`
+}
+
+// TargetCheckpoint 单个目标在凭据序列中的进度
+type TargetCheckpoint struct {
+	Position  int64        `json:"position"`            // 序号小于 Position 的任务均已完成
+	Completed []int64      `json:"completed,omitempty"` // 序号不小于 Position 且已完成的任务
+	Total     int64        `json:"total"`               // 目标的任务总数
+	Finished  bool         `json:"finished"`            // 目标是否已处理完毕
+	Abandoned bool         `json:"abandoned,omitempty"` // 目标是否被放弃
+	Reason    string       `json:"reason,omitempty"`    // 放弃原因
+	Successes []Credential `json:"successes,omitempty"` // 已发现的凭据
+
+	Skipped    int64             `json:"skipped,omitempty"`    // 因排除用户而跳过的任务数，已计入 Completed
+	Eliminated map[string]string `json:"eliminated,omitempty"` // 被排除的用户及原因
+}
+
+// completedCount 返回已完成的任务数
+func (t *TargetCheckpoint) completedCount() int64 {
+	return t.Position + int64(len(t.Completed))
+}
+
+// LoadCheckpoint 从文件加载检查点
+func LoadCheckpoint(filename string) (*Checkpoint, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+
+	checkpoint := &Checkpoint{}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint file: %w", err)
+	}
+	if checkpoint.Version != checkpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version: %d", checkpoint.Version)
+	}
+	if checkpoint.Targets == nil {
+		checkpoint.Targets = make(map[string]*TargetCheckpoint)
+	}
+
+	return checkpoint, nil
+}
+
+// Save 将检查点写入文件，先写临时文件再重命名，避免中断时留下损坏的文件
+func (c *Checkpoint) Save(filename string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+// progressTracker 记录目标已完成的任务序号
+// 任务并发完成时顺序不固定，因此保存一个低水位和水位之上的已完成序号
+type progressTracker struct {
+	position  int64
+	completed map[int64]struct{}
+}
+
+// complete 标记任务已完成并推进低水位
+func (t *progressTracker) complete(seq int64) {
+	if seq < t.position {
+		return
+	}
+	if t.completed == nil {
+		t.completed = make(map[int64]struct{})
+	}
+	t.completed[seq] = struct{}{}
+
+	for {
+		if _, ok := t.completed[t.position]; !ok {
+			break
+		}
+		delete(t.completed, t.position)
+		t.position++
+	}
+}
+
+// isCompleted 检查任务是否已完成
+func (t *progressTracker) isCompleted(seq int64) bool {
+	if seq < t.position {
+		return true
+	}
+	_, ok := t.completed[seq]
+	return ok
+}
+
+// count 返回已完成的任务数
+func (t *progressTracker) count() int64 {
+	return t.position + int64(len(t.completed))
+}
+
+// snapshot 返回低水位和排序后的已完成序号
+func (t *progressTracker) snapshot() (int64, []int64) {
+	completed := make([]int64, 0, len(t.completed))
+	for seq := range t.completed {
+		completed = append(completed, seq)
+	}
+	sort.Slice(completed, func(i, j int) bool { return completed[i] < completed[j] })
+	return t.position, completed
+}
+
+// Checkpoint 生成当前进度的检查点
+func (e *Engine) Checkpoint() *Checkpoint {
+	checkpoint := &Checkpoint{
+		Version:   checkpointVersion,
+		UpdatedAt: time.Now(),
+		Targets:   make(map[string]*TargetCheckpoint),
+	}
+
+	e.processes.Range(func(key, value interface{}) bool {
+		process := value.(*targetProcess)
+		process.mutex.RLock()
+		position, completed := process.tracker.snapshot()
+		checkpoint.Targets[process.Target] = &TargetCheckpoint{
+			Position:   position,
+			Completed:  completed,
+			Total:      process.total,
+			Finished:   process.Finished,
+			Abandoned:  process.Reason != "",
+			Reason:     process.Reason,
+			Successes:  append([]Credential(nil), process.successes...),
+			Skipped:    process.skipped,
+			Eliminated: maps.Clone(process.eliminated),
+		}
+		process.mutex.RUnlock()
+		return true
+	})
+
+	return checkpoint
+}
+
+// SaveCheckpoint 将当前进度写入检查点文件
+func (e *Engine) SaveCheckpoint(filename string) error {
+	return e.Checkpoint().Save(filename)
+}
+
+// Resume 从检查点文件恢复进度，需在 Start 之前调用
+// 引擎的目标和字典必须与生成检查点时一致，已完成的凭据组合不会再次尝试
+func (e *Engine) Resume(filename string) error {
+	checkpoint, err := LoadCheckpoint(filename)
+	if err != nil {
+		return err
+	}
+
+	e.resumeMutex.Lock()
+	e.restored = checkpoint.Targets
+	e.resumeMutex.Unlock()
+
+	var restoredTargets int
+	e.processes.Range(func(key, value interface{}) bool {
+		if e.restoreProcess(value.(*targetProcess)) {
+			restoredTargets++
+		}
+		return true
+	})
+
+	// 未指定检查点文件时继续写入原文件
+	if e.config.CheckpointFile == "" {
+		e.config.CheckpointFile = filename
+	}
+
+	gologger.Info().Msgf("Resumed %d targets from checkpoint %s", restoredTargets, filename)
+	return nil
+}
+
+// restoreProcess 将检查点中的进度应用到目标，返回是否找到对应的检查点
+func (e *Engine) restoreProcess(process *targetProcess) bool {
+	e.resumeMutex.Lock()
+	state, ok := e.restored[process.Target]
+	delete(e.restored, process.Target)
+	e.resumeMutex.Unlock()
+	if !ok {
+		return false
+	}
+
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+
+	if state.Total != process.total {
+		gologger.Warning().Msgf("Target %s has %d tasks but checkpoint recorded %d, dictionaries may have changed",
+			process.Target, process.total, state.Total)
+	}
+
+	process.tracker = progressTracker{position: state.Position}
+	for _, seq := range state.Completed {
+		process.tracker.complete(seq)
+	}
+	process.successes = append([]Credential(nil), state.Successes...)
+	process.skipped = state.Skipped
+	process.eliminated = maps.Clone(state.Eliminated)
+	process.Finished = state.Finished
+	process.Reason = state.Reason
+
+	// 恢复全局进度，跳过的任务不计入已处理
+	completed := state.completedCount()
+	successes := int64(len(state.Successes))
+	atomic.AddInt64(&e.processedItems, completed-state.Skipped)
+	atomic.AddInt64(&e.successItems, successes)
+	atomic.AddInt64(&e.failedItems, completed-state.Skipped-successes)
+	atomic.AddInt64(&e.skippedItems, state.Skipped)
+	if state.Abandoned && state.Total > completed {
+		process.remainderSkipped = true
+		atomic.AddInt64(&e.skippedItems, state.Total-completed)
+	}
+
+	for _, credential := range state.Successes {
+		gologger.Info().Msgf("Restored credential for %s: %s:%s", process.Target, credential.Username, credential.Password)
+	}
+	return true
+}
+
+// startCheckpointer 启动定时写入检查点的协程
+func (e *Engine) startCheckpointer() {
+	e.checkpointDone = make(chan struct{})
+	e.checkpointStopped = make(chan struct{})
+
+	interval := e.config.CheckpointInterval
+	if interval <= 0 {
+		interval = time.Second * 30
+	}
+
+	go func() {
+		defer close(e.checkpointStopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := e.SaveCheckpoint(e.config.CheckpointFile); err != nil {
+					gologger.Warning().Msgf("Failed to save checkpoint: %v", err)
+				}
+			case <-e.checkpointDone:
+				return
+			}
+		}
+	}()
+}
+
+// stopCheckpointer 停止定时写入并保存最终检查点
+func (e *Engine) stopCheckpointer() {
+	close(e.checkpointDone)
+	<-e.checkpointStopped
+
+	if err := e.SaveCheckpoint(e.config.CheckpointFile); err != nil {
+		gologger.Warning().Msgf("Failed to save checkpoint: %v", err)
+		return
+	}
+	gologger.Info().Msgf("Checkpoint saved to %s", e.config.CheckpointFile)
+}
@@ -0,0 +1,82 @@
+package brute
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// updateCircuit 根据结果更新目标的熔断状态
+// 协议处理器报告 Finished，或连续网络失败达到 FinishingThreshold 时放弃目标
+func (e *Engine) updateCircuit(process *targetProcess, result *BruteResult) {
+	if result.Finished {
+		e.abandonTarget(process, "handler reported finished: "+describeFailure(result))
+		return
+	}
+
+	if !result.FailureKind.IsNetwork() {
+		atomic.StoreInt32(&process.failures, 0)
+		return
+	}
+
+	failures := atomic.AddInt32(&process.failures, 1)
+	if threshold := e.config.FinishingThreshold; threshold > 0 && int(failures) >= threshold {
+		e.abandonTarget(process, fmt.Sprintf("%d consecutive network failures, last: %s", failures, describeFailure(result)))
+	}
+}
+
+// describeFailure 返回失败类型和错误的简要描述
+func describeFailure(result *BruteResult) string {
+	if result.Error == nil {
+		return result.FailureKind.String()
+	}
+	return fmt.Sprintf("%s (%v)", result.FailureKind, result.Error)
+}
+
+// abandonTarget 放弃目标并取消其尚未完成的任务，返回是否为首次放弃
+func (e *Engine) abandonTarget(process *targetProcess, reason string) bool {
+	process.mutex.Lock()
+	if process.Reason != "" {
+		process.mutex.Unlock()
+		return false
+	}
+	process.Reason = reason
+	process.Finished = true
+	process.mutex.Unlock()
+
+	process.cancel()
+	gologger.Warning().Msgf("Abandoning target %s: %s", process.Target, reason)
+	e.publish(Event{Type: EventTargetSkipped, Target: process.Target, Reason: reason})
+	return true
+}
+
+// countSkipped 目标处理结束后，将被放弃的目标的剩余任务计为跳过
+func (e *Engine) countSkipped(process *targetProcess) {
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+
+	if process.Reason == "" || process.remainderSkipped {
+		return
+	}
+	process.remainderSkipped = true
+	if remaining := process.total - process.tracker.count(); remaining > 0 {
+		atomic.AddInt64(&e.skippedItems, remaining)
+	}
+}
+
+// GetAbandonedTargets 获取被放弃的目标及原因
+func (e *Engine) GetAbandonedTargets() map[string]string {
+	abandoned := make(map[string]string)
+	e.processes.Range(func(key, value interface{}) bool {
+		process := value.(*targetProcess)
+		process.mutex.RLock()
+		// 无需认证的目标跳过了凭据，不属于被放弃的目标
+		if process.Reason != "" && !process.unauthenticated {
+			abandoned[process.Target] = process.Reason
+		}
+		process.mutex.RUnlock()
+		return true
+	})
+	return abandoned
+}
@@ -0,0 +1,147 @@
+package brute
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+	"golang.org/x/time/rate"
+)
+
+// PauseScheduling 暂停调度，正在进行的尝试会正常完成，已分配槽位的任务在恢复前不会发起新的尝试
+func (e *Engine) PauseScheduling() {
+	e.pauseMutex.Lock()
+	defer e.pauseMutex.Unlock()
+	if e.paused {
+		return
+	}
+	e.paused = true
+	e.resumed = make(chan struct{})
+	gologger.Info().Msg("Scheduling paused")
+}
+
+// ResumeScheduling 恢复被暂停的调度
+func (e *Engine) ResumeScheduling() {
+	e.pauseMutex.Lock()
+	defer e.pauseMutex.Unlock()
+	if !e.paused {
+		return
+	}
+	e.paused = false
+	close(e.resumed)
+	gologger.Info().Msg("Scheduling resumed")
+}
+
+// IsPaused 检查调度是否已暂停
+func (e *Engine) IsPaused() bool {
+	e.pauseMutex.Lock()
+	defer e.pauseMutex.Unlock()
+	return e.paused
+}
+
+// waitResumed 暂停期间阻塞，上下文取消时返回 false
+func (e *Engine) waitResumed(ctx context.Context) bool {
+	e.pauseMutex.Lock()
+	resumed := e.resumed
+	e.pauseMutex.Unlock()
+
+	select {
+	case <-resumed:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// SetConcurrency 在运行时调整全局并发数和单目标并发数，未设置 MaxActiveTargets 时同时处理的目标数随全局并发数调整
+// 调小时不会中断正在进行的尝试，占用数降到新的上限以下之前不再发放新的槽位
+// 启用自适应并发时，单目标并发数作为自适应调整的上限，所有目标的当前并发数重置为该值
+func (e *Engine) SetConcurrency(targetConcurrent, taskConcurrent int) error {
+	if targetConcurrent <= 0 {
+		return fmt.Errorf("target concurrent must be positive, got: %d", targetConcurrent)
+	}
+	if taskConcurrent <= 0 {
+		return fmt.Errorf("task concurrent must be positive, got: %d", taskConcurrent)
+	}
+
+	e.globalSem.resize(targetConcurrent)
+	e.setActiveLimit(activeTargetLimit(e.config, targetConcurrent))
+	atomic.StoreInt64(&e.taskConcurrent, int64(taskConcurrent))
+
+	e.processes.Range(func(key, value interface{}) bool {
+		process := value.(*targetProcess)
+		if c := process.adaptive; c != nil {
+			c.mutex.Lock()
+			c.concurrency = taskConcurrent
+			process.semaphore.resize(taskConcurrent)
+			c.mutex.Unlock()
+		} else {
+			process.semaphore.resize(taskConcurrent)
+		}
+		return true
+	})
+
+	gologger.Info().Msgf("Concurrency updated: TargetConcurrent=%d, TaskConcurrent=%d", targetConcurrent, taskConcurrent)
+	return nil
+}
+
+// getTaskConcurrent 返回当前的单目标并发数
+func (e *Engine) getTaskConcurrent() int {
+	return int(atomic.LoadInt64(&e.taskConcurrent))
+}
+
+// SetDelay 在运行时调整同一目标相邻请求之间的延迟，maxDelay 大于 minDelay 时每次随机取值
+// 未设置全局限流时 minDelay 同时是全局速率
+func (e *Engine) SetDelay(minDelay, maxDelay time.Duration) error {
+	if minDelay < 0 {
+		return fmt.Errorf("min delay cannot be negative, got: %v", minDelay)
+	}
+	if maxDelay > 0 && minDelay > maxDelay {
+		return fmt.Errorf("min delay (%v) cannot be greater than max delay (%v)", minDelay, maxDelay)
+	}
+
+	atomic.StoreInt64(&e.minDelay, int64(minDelay))
+	atomic.StoreInt64(&e.maxDelay, int64(maxDelay))
+	// 全局速率由 MinDelay 换算而来时一并调整，未设置延迟时不再限制
+	if e.limiters.fromDelay.Load() {
+		limit := rate.Inf
+		if minDelay > 0 {
+			limit = rate.Every(minDelay)
+		}
+		e.limiters.global.SetLimit(limit)
+	}
+	gologger.Info().Msgf("Delay updated: %v-%v", minDelay, max(minDelay, maxDelay))
+	return nil
+}
+
+// GetDelay 获取当前同一目标相邻请求之间的延迟
+func (e *Engine) GetDelay() (minDelay, maxDelay time.Duration) {
+	minDelay = time.Duration(atomic.LoadInt64(&e.minDelay))
+	maxDelay = time.Duration(atomic.LoadInt64(&e.maxDelay))
+	return minDelay, max(minDelay, maxDelay)
+}
+
+// SetRateLimit 在运行时调整一层限流，速率为 0 表示不限制
+// 主机、子网和协议层已创建的限流器一并更新，之后加入的目标使用新的设置
+func (e *Engine) SetRateLimit(layer RateLayer, limit RateLimit) error {
+	if err := limit.validate(); err != nil {
+		return fmt.Errorf("invalid rate limit: %w", err)
+	}
+	if err := e.limiters.set(layer, limit); err != nil {
+		return err
+	}
+
+	limiter := limit.newLimiter()
+	gologger.Info().Msgf("Rate limit updated for %s: %s", layer, formatRateLimit(limiter.Limit(), limiter.Burst()))
+	return nil
+}
+
+// formatRateLimit 格式化限流设置
+func formatRateLimit(limit rate.Limit, burst int) string {
+	if limit == rate.Inf {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%.2f/s (burst %d)", float64(limit), burst)
+}
@@ -0,0 +1,53 @@
+package brute
+
+import (
+	"sync/atomic"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// updateElimination 根据结果排除用户，找到密码或处理器报告用户不可用时，该用户的剩余密码不再尝试
+func (e *Engine) updateElimination(process *targetProcess, result *BruteResult) {
+	var reason string
+	switch {
+	case result.Success:
+		reason = "password found"
+	case result.UserEliminated:
+		reason = describeFailure(result)
+	default:
+		return
+	}
+
+	if process.eliminate(result.Item.Username, reason) {
+		gologger.Info().Msgf("User %q eliminated on %s: %s", result.Item.Username, process.Target, reason)
+	}
+}
+
+// eliminate 排除目标上的用户，返回是否为首次排除
+func (p *targetProcess) eliminate(username, reason string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, ok := p.eliminated[username]; ok {
+		return false
+	}
+	if p.eliminated == nil {
+		p.eliminated = make(map[string]string)
+	}
+	p.eliminated[username] = reason
+	return true
+}
+
+// skipEliminated 跳过已被排除的用户的任务，跳过的任务视为已完成
+func (e *Engine) skipEliminated(process *targetProcess, item *BruteItem) bool {
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+
+	if _, ok := process.eliminated[item.Username]; !ok {
+		return false
+	}
+	process.tracker.complete(item.Seq)
+	process.skipped++
+	atomic.AddInt64(&e.skippedItems, 1)
+	return true
+}
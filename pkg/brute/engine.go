@@ -1,10 +1,11 @@
 package brute
 
 import (
-	"bufio"
 	"container/list"
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"sync"
@@ -20,34 +21,115 @@ import (
 type Engine struct {
 	config         *Config
 	targets        *list.List
+	targetsMutex   sync.RWMutex  // 保护 targets、launched 和关闭状态，运行中添加目标和任务时持有读锁
+	launched       bool          // 是否已开始调度
+	closed         chan struct{} // 关闭后不再接受新的目标和任务
+	closeOnce      sync.Once
+	waitOnce       sync.Once
 	processes      sync.Map
-	limiter        *rate.Limiter
-	globalSem      chan struct{} // 全局并发控制信号量
+	realms         sync.Map      // 密码喷洒域，域名 -> *sprayRealm
+	limiters       *rateLimiters // 分层限流器
+	globalSem      *semaphore    // 全局并发控制信号量
+	probeSem       chan struct{} // 存活探测并发控制信号量
 	ctx            context.Context
 	cancel         context.CancelFunc
 	wg             sync.WaitGroup
 	targetWg       sync.WaitGroup
 	resultCallback ResultCallback // 结果回调函数
 
+	// 目标调度相关字段
+	queueMutex    sync.Mutex   // 保护 queue、activeTargets、activeLimit 和 dispatching
+	queue         *targetQueue // 等待工作槽位的目标
+	activeTargets int          // 占用工作槽位的目标数
+	activeLimit   int          // 同时处理的目标数上限
+	dispatching   bool         // Launch 之后开始从队列中调度目标
+
+	// 事件订阅相关字段
+	subsMutex     sync.RWMutex    // 保护 subscriptions 和 eventsClosed
+	subscriptions []*Subscription // 事件订阅者
+	eventsClosed  bool            // EngineDone 之后不再接受订阅
+
+	// 运行时控制相关字段
+	pauseMutex     sync.Mutex    // 保护 paused 和 resumed
+	paused         bool          // 是否暂停调度
+	resumed        chan struct{} // 未暂停时为已关闭的通道
+	taskConcurrent int64         // 当前单目标并发数，可在运行时调整
+	minDelay       int64         // 当前同一目标相邻请求的最小延迟（纳秒）
+	maxDelay       int64         // 当前同一目标相邻请求的最大延迟（纳秒）
+
 	// 进度跟踪相关字段
-	totalItems     int64         // 总任务数
-	processedItems int64         // 已处理任务数
-	successItems   int64         // 成功任务数
-	failedItems    int64         // 失败任务数
-	startTime      time.Time     // 开始时间
-	progressTicker *time.Ticker  // 进度打印定时器
-	progressDone   chan struct{} // 进度打印停止信号
-	progressMutex  sync.RWMutex  // 进度相关的读写锁
+	totalItems      int64         // 总任务数
+	processedItems  int64         // 已处理任务数
+	successItems    int64         // 成功任务数
+	failedItems     int64         // 失败任务数
+	retriedItems    int64         // 重试次数
+	skippedItems    int64         // 因目标被放弃而跳过的任务数
+	sessionsOpened  int64         // 会话复用模式下建立的会话数
+	sessionReuses   int64         // 复用已有会话的尝试次数
+	unauthTargets   int64         // 无需认证即可访问的目标数
+	failureCounts   sync.Map      // 各失败类型的次数，FailureKind -> *int64
+	protocolMetrics sync.Map      // 各协议的尝试统计，协议 -> *protocolMetrics
+	metricsServer   *http.Server  // 指标 HTTP 服务
+	startTime       time.Time     // 开始时间
+	progressTicker  *time.Ticker  // 进度打印定时器
+	progressDone    chan struct{} // 进度打印停止信号
+	progressMutex   sync.RWMutex  // 进度相关的读写锁
+
+	// 断点续传相关字段
+	resumeMutex       sync.Mutex                   // 保护 restored
+	restored          map[string]*TargetCheckpoint // 待恢复的目标进度
+	checkpointDone    chan struct{}                // 检查点写入停止信号
+	checkpointStopped chan struct{}                // 检查点写入协程已退出
 }
 
 // targetProcess 目标处理状态
 type targetProcess struct {
 	Target    string
-	Items     []*BruteItem
+	Items     []*BruteItem // 通过 Feed 逐个提交的任务项
+	sources   []ItemSource // 惰性任务源，按需产生任务项
 	Count     int32
 	Finished  bool
+	Reason    string // 目标被放弃的原因
 	mutex     sync.RWMutex
-	semaphore chan struct{}
+	semaphore *semaphore // 目标级别的并发控制，容量随自适应控制调整
+
+	serviceType string // 服务类型
+	host        string // 目标地址
+	port        int    // 目标端口
+
+	ctx      context.Context    // 目标级别的上下文，放弃目标时取消
+	cancel   context.CancelFunc // 取消目标的所有任务
+	failures int32              // 连续网络失败次数
+
+	items   sync.WaitGroup // 目标正在进行的任务
+	started bool           // 是否已开始处理，只由占用工作槽位的协程访问
+	parked  bool           // 暂时没有任务而挂起，不占用工作槽位和协程
+
+	eliminated       map[string]string // 被排除的用户及原因，这些用户的剩余任务直接跳过
+	skipped          int64             // 因排除用户而跳过的任务数
+	remainderSkipped bool              // 放弃目标后剩余任务是否已计为跳过
+
+	total      int64           // 任务总数
+	drawn      int64           // 已取出的任务数，用于分配任务序号
+	tracker    progressTracker // 已完成任务的序号
+	successes  []Credential    // 已发现的凭据
+	statistics targetStats     // 目标的尝试统计
+
+	realm      string      // 喷洒域，为空时使用目标地址
+	sprayRealm *sprayRealm // 密码喷洒模式下目标所在的域
+	round      int         // 当前喷洒轮次
+	spraying   bool        // 是否处于域内已开启的窗口中，只由占用工作槽位的协程访问
+	held       *BruteItem  // 等待喷洒窗口时保留的任务项，重新获得槽位后首先处理
+
+	limiters    []*rate.Limiter     // 目标需要经过的各层限流器
+	nextAttempt time.Time           // 目标下一次请求最早的开始时间
+	adaptive    *adaptiveController // 自适应并发控制，未启用时为 nil
+	sessions    sessionPool         // 会话复用模式下的空闲会话
+
+	verifyMutex sync.Mutex    // 串行化认证成功后的随机密码验证
+	verified    verifyVerdict // 随机密码验证的结论，没有结论时为 verifyUnknown
+
+	unauthenticated bool // 未授权探测发现目标无需认证即可访问
 }
 
 // NewEngine 创建新的爆破引擎
@@ -67,18 +149,25 @@ func NewEngine(ctx context.Context, config *Config) (*Engine, error) {
 
 	ctx, cancel := context.WithCancel(ctx)
 
-	// 创建限流器 - 修复：使用正确的速率参数
-	// rate.Every(config.MinDelay) 表示每隔 MinDelay 时间允许一个请求
-	// config.TargetConcurrent 表示突发容量（桶的大小）
-	limiter := rate.NewLimiter(rate.Every(config.MinDelay), config.TargetConcurrent)
+	// 创建分层限流器，MinDelay 和 MaxDelay 控制同一目标相邻请求之间的随机间隔
 	engine := &Engine{
 		config:    config,
 		targets:   list.New(),
-		limiter:   limiter,
+		limiters:  newRateLimiters(config),
+		closed:    make(chan struct{}),
 		ctx:       ctx,
 		cancel:    cancel,
-		globalSem: make(chan struct{}, config.TargetConcurrent), // 全局并发控制
+		globalSem: newSemaphore(config.TargetConcurrent), // 全局并发控制
+		resumed:   make(chan struct{}),
+		queue:     newTargetQueue(config.TargetOrder, config.ShuffleSeed),
+
+		activeLimit:    activeTargetLimit(config, config.TargetConcurrent),
+		taskConcurrent: int64(config.TaskConcurrent),
+		minDelay:       int64(config.MinDelay),
+		maxDelay:       int64(config.MaxDelay),
 	}
+	close(engine.resumed)
+	engine.probeSem = make(chan struct{}, max(config.PreCheckConcurrent, 1))
 
 	return engine, nil
 }
@@ -88,22 +177,65 @@ func (e *Engine) SetResultCallback(callback ResultCallback) {
 	e.resultCallback = callback
 }
 
-// AddTarget 添加目标
-func (e *Engine) AddTarget(serviceType, target string, port int) {
+// AddTarget 添加目标，可以在 Launch 之后调用，已存在的目标会被忽略
+func (e *Engine) AddTarget(serviceType, target string, port int) error {
+	return e.AddTargetInRealm(serviceType, target, port, "")
+}
+
+// AddTargetInRealm 添加属于指定喷洒域的目标，共享账号锁定策略的目标应使用同一个域，为空时使用目标地址
+func (e *Engine) AddTargetInRealm(serviceType, target string, port int, realm string) error {
+	e.targetsMutex.Lock()
+	defer e.targetsMutex.Unlock()
+	if e.isClosed() {
+		return fmt.Errorf("engine is closed")
+	}
+
 	targetKey := fmt.Sprintf("%s:%s:%d", serviceType, target, port)
-	e.targets.PushBack(targetKey)
+	if _, exists := e.processes.Load(targetKey); exists {
+		return nil
+	}
 
 	// 初始化目标处理器
 	process := &targetProcess{
-		Target:    targetKey,
-		Items:     make([]*BruteItem, 0),
-		semaphore: make(chan struct{}, e.config.TaskConcurrent),
+		Target:      targetKey,
+		serviceType: serviceType,
+		host:        target,
+		port:        port,
+		realm:       realm,
+		Items:       make([]*BruteItem, 0),
+		semaphore:   newSemaphore(e.getTaskConcurrent()),
+	}
+	if e.config.Adaptive {
+		process.adaptive = newAdaptiveController(e.getTaskConcurrent())
 	}
+	process.ctx, process.cancel = context.WithCancel(e.ctx)
+	process.limiters = e.limiters.forTarget(serviceType, target)
+	e.targets.PushBack(targetKey)
 	e.processes.Store(targetKey, process)
+
+	// 目标排队等待工作槽位，Launch 之后开始调度
+	e.enqueueTarget(process)
+	return nil
 }
 
-// Feed 向引擎提供爆破任务
+// isClosed 检查引擎是否已关闭输入
+func (e *Engine) isClosed() bool {
+	select {
+	case <-e.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Feed 向引擎提供爆破任务，可以在 Launch 之后、Close 之前调用
 func (e *Engine) Feed(item *BruteItem) error {
+	e.targetsMutex.RLock()
+	defer e.targetsMutex.RUnlock()
+	if e.isClosed() {
+		return fmt.Errorf("engine is closed")
+	}
+
 	targetKey := fmt.Sprintf("%s:%s:%d", item.Type, item.Target, item.Port)
 
 	processRaw, ok := e.processes.Load(targetKey)
@@ -113,29 +245,99 @@ func (e *Engine) Feed(item *BruteItem) error {
 
 	process := processRaw.(*targetProcess)
 	process.mutex.Lock()
+	if process.Finished {
+		process.mutex.Unlock()
+		return fmt.Errorf("target %s already finished", targetKey)
+	}
 	process.Items = append(process.Items, item)
+	process.total++
+	wake := process.unpark()
 	process.mutex.Unlock()
 
 	// 更新总任务数
 	atomic.AddInt64(&e.totalItems, 1)
+	if wake {
+		e.enqueueTarget(process)
+	}
 
 	return nil
 }
 
-// Start 开始爆破
+// FeedSource 向引擎提供惰性任务源，任务项在工作协程空闲时才会生成
+func (e *Engine) FeedSource(serviceType, target string, port int, source ItemSource) error {
+	e.targetsMutex.RLock()
+	defer e.targetsMutex.RUnlock()
+	if e.isClosed() {
+		return fmt.Errorf("engine is closed")
+	}
+
+	targetKey := fmt.Sprintf("%s:%s:%d", serviceType, target, port)
+
+	processRaw, ok := e.processes.Load(targetKey)
+	if !ok {
+		return fmt.Errorf("target %s not found", targetKey)
+	}
+
+	process := processRaw.(*targetProcess)
+	process.mutex.Lock()
+	if process.Finished {
+		process.mutex.Unlock()
+		return fmt.Errorf("target %s already finished", targetKey)
+	}
+	process.sources = append(process.sources, source)
+	process.total += source.Total()
+	wake := process.unpark()
+	process.mutex.Unlock()
+
+	// 更新总任务数
+	atomic.AddInt64(&e.totalItems, source.Total())
+	if wake {
+		e.enqueueTarget(process)
+	}
+
+	return nil
+}
+
+// Start 开始爆破，处理所有已添加的目标后返回
 func (e *Engine) Start() error {
+	if err := e.Launch(); err != nil {
+		return err
+	}
+	e.Close()
+	return e.Wait()
+}
+
+// Launch 在后台开始爆破并立即返回，之后仍可以通过 AddTarget、Feed 和 FeedSource 添加目标和任务
+// 所有输入提交完毕后调用 Close，再调用 Wait 等待任务完成
+func (e *Engine) Launch() error {
+	e.targetsMutex.Lock()
+	defer e.targetsMutex.Unlock()
+	if e.launched {
+		return fmt.Errorf("engine already launched")
+	}
+
+	// 启动指标服务（如果启用），监听失败时不开始爆破
+	if e.config.MetricsListen != "" {
+		if err := e.startMetricsServer(); err != nil {
+			return err
+		}
+	}
+	e.launched = true
+
 	gologger.Info().Msg("Starting brute force engine")
-	gologger.Info().Msgf("Configuration: TargetConcurrent=%d, TaskConcurrent=%d, MinDelay=%v",
-		e.config.TargetConcurrent, e.config.TaskConcurrent, e.config.MinDelay)
+	gologger.Info().Msgf("Configuration: TargetConcurrent=%d, TaskConcurrent=%d, ActiveTargets=%d, TargetOrder=%s, Delay=%v-%v",
+		e.config.TargetConcurrent, e.getTaskConcurrent(), activeTargetLimit(e.config, e.config.TargetConcurrent),
+		e.queue.order, e.config.MinDelay, max(e.config.MinDelay, e.config.MaxDelay))
 
-	targetCount := e.targets.Len()
-	if targetCount == 0 {
+	if e.targets.Len() == 0 {
 		gologger.Warning().Msg("No targets to process")
-		return nil
 	}
-
 	totalTasks := atomic.LoadInt64(&e.totalItems)
-	gologger.Info().Msgf("Processing %d targets with %d total tasks", targetCount, totalTasks)
+	gologger.Info().Msgf("Processing %d targets with %d total tasks", e.targets.Len(), totalTasks)
+	if e.config.Strategy == StrategySpray {
+		gologger.Info().Msgf("Password spraying: %d password(s) per round, observation window %v",
+			e.config.SprayPerRound, e.config.SprayWindow)
+	}
 
 	// 记录开始时间
 	e.startTime = time.Now()
@@ -145,22 +347,80 @@ func (e *Engine) Start() error {
 		e.startProgressTicker()
 	}
 
-	// 遍历所有目标
-	for element := e.targets.Front(); element != nil; element = element.Next() {
-		targetKey := element.Value.(string)
-		e.targetWg.Add(1)
-		go e.processTarget(targetKey)
+	// 启动检查点定时写入（如果启用）
+	if e.config.CheckpointFile != "" {
+		e.startCheckpointer()
 	}
 
-	// 等待所有目标处理完成
-	e.targetWg.Wait()
-	// 停止进度打印
-	if e.config.ShowProgress {
-		e.stopProgressTicker()
+	// 按调度顺序为排队的目标分配工作槽位
+	e.startDispatching()
+	return nil
+}
+
+// Close 通知引擎不再有新的目标和任务，可以重复调用
+func (e *Engine) Close() {
+	e.closeOnce.Do(func() {
+		// 等待正在进行的 AddTarget 和 Feed 完成
+		e.targetsMutex.Lock()
+		defer e.targetsMutex.Unlock()
+
+		// 挂起的目标重新排队以结束处理，需在关闭前加入等待组
+		for element := e.targets.Front(); element != nil; element = element.Next() {
+			processRaw, _ := e.processes.Load(element.Value.(string))
+			process := processRaw.(*targetProcess)
+			process.mutex.Lock()
+			wake := process.unpark()
+			process.mutex.Unlock()
+			if wake {
+				e.enqueueTarget(process)
+			}
+		}
+		close(e.closed)
+	})
+}
+
+// Wait 等待 Close 之前提交的所有任务完成，引擎被停止时等待正在进行的尝试结束后返回
+func (e *Engine) Wait() error {
+	e.targetsMutex.RLock()
+	launched := e.launched
+	e.targetsMutex.RUnlock()
+	if !launched {
+		return fmt.Errorf("engine not launched")
+	}
+
+	select {
+	case <-e.closed:
+	case <-e.ctx.Done():
+		// 引擎被停止时不再接受新的输入
+		e.Close()
 	}
 
-	// 打印最终统计信息
-	e.printFinalStats()
+	e.waitOnce.Do(func() {
+		// 等待所有目标处理完成，包括中断时仍在进行的尝试
+		e.targetWg.Wait()
+		e.wg.Wait()
+
+		// 保存最终检查点
+		if e.config.CheckpointFile != "" {
+			e.stopCheckpointer()
+		}
+
+		// 停止进度打印
+		if e.config.ShowProgress {
+			e.stopProgressTicker()
+		}
+
+		// 停止指标服务
+		if e.metricsServer != nil {
+			e.stopMetricsServer()
+		}
+
+		// 打印最终统计信息
+		e.printFinalStats()
+
+		// 通知订阅者引擎已完成
+		e.publishDone()
+	})
 
 	return nil
 }
@@ -172,83 +432,305 @@ func (e *Engine) Stop() {
 	e.wg.Wait()
 }
 
-// processTarget 处理单个目标
-func (e *Engine) processTarget(targetKey string) {
+// processTarget 在工作槽位中处理目标，目标暂时没有任务时挂起并让出槽位
+func (e *Engine) processTarget(process *targetProcess) {
 	defer e.targetWg.Done()
+	defer e.releaseTargetSlot()
 
-	processRaw, ok := e.processes.Load(targetKey)
-	if !ok {
-		gologger.Error().Msgf("Target process not found: %s", targetKey)
-		return
+	// 首次获得槽位时恢复进度并进行存活探测，挂起后重新排队的目标直接继续
+	if !process.started {
+		process.started = true
+
+		// 恢复检查点中的进度，已完成的目标直接跳过
+		e.restoreProcess(process)
+		process.mutex.RLock()
+		finished := process.Finished
+		process.mutex.RUnlock()
+		if finished {
+			gologger.Debug().Msgf("Target %s already finished in checkpoint, skipping", process.Target)
+			e.leaveRealm(process)
+			process.closeSources()
+			e.publish(Event{Type: EventTargetSkipped, Target: process.Target, Reason: "finished in checkpoint"})
+			return
+		}
+
+		e.publish(Event{Type: EventTargetStarted, Target: process.Target})
+
+		// 存活探测，不可达的目标不再调度凭据
+		if e.config.PreCheck && !e.precheckTarget(process) {
+			e.finishTarget(process)
+			return
+		}
+
+		// 未授权访问探测，服务已开放时不再尝试凭据
+		if e.config.UnauthProbe && e.probeUnauth(process) {
+			e.finishTarget(process)
+			return
+		}
 	}
 
-	process := processRaw.(*targetProcess)
-	var itemWg sync.WaitGroup
+	if e.runTarget(process) {
+		return
+	}
+	e.finishTarget(process)
+}
 
-	// 处理所有任务项
-	for _, item := range process.Items {
+// runTarget 按需拉取任务项，直到任务源耗尽或目标被放弃，返回 true 表示目标已挂起
+func (e *Engine) runTarget(process *targetProcess) bool {
+	for {
 		// 检查上下文
-		select {
-		case <-e.ctx.Done():
-			return
-		default:
+		if e.ctx.Err() != nil {
+			return false
+		}
+
+		// 先获取并发槽位再拉取任务项，调度时总能看到最新的用户排除状态
+		if !e.acquire(process) {
+			return false
 		}
 
+		item, ok := e.nextPending(process)
+		if !ok {
+			e.release(process)
+			// 等待进行中的尝试结束，长期运行模式下挂起目标等待新的任务
+			process.items.Wait()
+			parked, pending := e.park(process)
+			if pending {
+				continue
+			}
+			// 挂起期间不保留空闲的会话
+			process.sessions.drain(!parked)
+			return parked
+		}
+
+		// 密码喷洒模式下进入新一轮前，等待同一域内的目标完成当前窗口并度过观察窗口
+		if process.sprayRealm != nil && (!process.spraying || item.Round > process.round) {
+			e.release(process)
+			switch e.enterSprayRound(process, item) {
+			case sprayStop:
+				return false
+			case sprayWait:
+				// 等待窗口期间不保留空闲的会话
+				process.sessions.drain(false)
+				return true
+			}
+			if !e.acquire(process) {
+				return false
+			}
+		}
+
+		// 任务在目标级别的上下文中执行，放弃目标时一并取消
+		item.Context = process.ctx
+
+		process.items.Add(1)
+		e.wg.Add(1)
+		gologger.Debug().Msgf("Processing target: %s service: %s username:%s password:%s",
+			process.Target, item.Type, item.Username, item.Password)
+		go e.processItem(item, process, &process.items)
+	}
+}
+
+// finishTarget 等待目标的所有任务完成后结束处理
+func (e *Engine) finishTarget(process *targetProcess) {
+	process.items.Wait()
+
+	// 未被中断时标记目标已处理完毕，断点续传时直接跳过
+	if e.ctx.Err() == nil {
+		process.mutex.Lock()
+		process.Finished = true
+		process.mutex.Unlock()
+	}
+
+	// 被放弃的目标的剩余任务计为跳过
+	e.countSkipped(process)
+	e.leaveRealm(process)
+	process.closeSources()
+	process.sessions.drain(true)
+
+	// 所有尝试的事件都先于目标结束事件发送
+	process.mutex.RLock()
+	reason := process.Reason
+	process.mutex.RUnlock()
+	e.publish(Event{Type: EventTargetFinished, Target: process.Target, Reason: reason})
+	gologger.Debug().Msgf("Target %s processing completed", process.Target)
+}
+
+// acquire 依次获取全局和目标级别的信号量，目标被放弃或引擎停止时返回 false
+// 暂停期间不会发放新的槽位
+func (e *Engine) acquire(process *targetProcess) bool {
+	if !e.waitResumed(process.ctx) {
+		return false
+	}
+
+	// 获取全局信号量，控制整体并发数
+	if !e.globalSem.acquire(process.ctx) {
+		return false
+	}
+
+	// 然后获取目标级别的信号量，控制单个目标的并发数
+	if !process.semaphore.acquire(process.ctx) {
+		e.globalSem.release() // 释放全局信号量
+		return false
+	}
+	return true
+}
+
+// release 释放目标级别和全局的信号量
+func (e *Engine) release(process *targetProcess) {
+	process.semaphore.release() // 释放目标级别信号量
+	e.globalSem.release()       // 释放全局信号量
+}
+
+// nextPending 取出下一个需要执行的任务项，跳过已完成的任务和被排除的用户
+func (e *Engine) nextPending(process *targetProcess) (*BruteItem, bool) {
+	for {
 		// 检查是否需要提前停止
 		process.mutex.RLock()
 		finished := process.Finished
 		process.mutex.RUnlock()
 		if finished {
-			break
+			return nil, false
 		}
 
-		// 获取全局信号量，控制整体并发数
-		select {
-		case e.globalSem <- struct{}{}:
-			// 然后获取目标级别的信号量，控制单个目标的并发数
-			select {
-			case process.semaphore <- struct{}{}:
-				itemWg.Add(1)
-				e.wg.Add(1)
-				gologger.Debug().Msgf("Processing target: %s service: %s username:%s password:%s",
-					targetKey, item.Type, item.Username, item.Password)
-				go e.processItem(item, process, &itemWg)
-			case <-e.ctx.Done():
-				<-e.globalSem // 释放全局信号量
-				return
-			}
-		case <-e.ctx.Done():
-			return
+		item, ok := process.next()
+		if !ok {
+			return nil, false
+		}
+
+		// 跳过检查点中已完成的任务和已被排除的用户，等待喷洒窗口期间上一轮的结果可能已经排除了该用户
+		if process.isCompleted(item.Seq) || e.skipEliminated(process, item) {
+			continue
 		}
+		return item, true
 	}
+}
+
+// next 取出下一个待处理的任务项，先处理等待喷洒窗口时保留的任务项和 Feed 提交的任务项，再依次拉取任务源
+func (p *targetProcess) next() (*BruteItem, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if item := p.held; item != nil {
+		p.held = nil
+		return item, true
+	}
+
+	if len(p.Items) > 0 {
+		item := p.Items[0]
+		p.Items[0] = nil
+		p.Items = p.Items[1:]
+		item.Seq = p.drawn
+		p.drawn++
+		return item, true
+	}
+
+	for len(p.sources) > 0 {
+		if item, ok := p.sources[0].Next(); ok {
+			item.Seq = p.drawn
+			p.drawn++
+			return item, true
+		}
+		p.sources[0].Close()
+		p.sources = p.sources[1:]
+	}
+	return nil, false
+}
 
-	// 等待当前目标的所有任务完成
-	itemWg.Wait()
-	gologger.Debug().Msgf("Target %s processing completed", targetKey)
+// isCompleted 检查任务是否已在之前的运行中完成
+func (p *targetProcess) isCompleted(seq int64) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.tracker.isCompleted(seq)
+}
+
+// complete 记录任务已完成
+func (p *targetProcess) complete(result *BruteResult) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.tracker.complete(result.Item.Seq)
+	if result.Success {
+		p.successes = append(p.successes, Credential{
+			Username: result.Item.Username,
+			Password: result.Item.Password,
+		})
+	}
+}
+
+// closeSources 关闭所有剩余的任务源
+func (p *targetProcess) closeSources() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, source := range p.sources {
+		source.Close()
+	}
+	p.sources = nil
+	p.Items = nil
 }
 
 // processItem 处理单个爆破项
 func (e *Engine) processItem(item *BruteItem, process *targetProcess, itemWg *sync.WaitGroup) {
 	defer e.wg.Done()
 	defer itemWg.Done()
-	defer func() {
-		<-process.semaphore // 释放目标级别信号量
-		<-e.globalSem       // 释放全局信号量
-	}()
+	defer e.release(process)
+
+	// 执行爆破，瞬时网络错误按指数退避重试
+	var result *BruteResult
+	for attempt := 1; ; attempt++ {
+		// 限流 - 等待请求间隔和各层限流器允许
+		if !e.throttle(process) {
+			gologger.Debug().Msgf("Rate limiter wait canceled for target %s", process.Target)
+			return
+		}
 
-	// 限流 - 等待限流器允许
-	if err := e.limiter.Wait(e.ctx); err != nil {
-		gologger.Debug().Msgf("Rate limiter wait failed: %v", err)
-		return
+		startTime := time.Now()
+		result = e.executeItem(item, process)
+		result.ResponseTime = time.Since(startTime)
+		result.Attempts = attempt
+		result.normalize()
+
+		// 根据超时和拒绝连接的比例调整目标的并发数和延迟
+		e.adjustConcurrency(process, result.FailureKind)
+
+		// 只有超时、连接重置等瞬时错误才会重试，认证被拒绝等明确结论不会重试
+		if result.Success || attempt > e.config.MaxRetries || !result.FailureKind.Retryable() {
+			break
+		}
+
+		backoff := e.retryBackoff(attempt)
+		atomic.AddInt64(&e.retriedItems, 1)
+		gologger.Debug().Msgf("Retrying %s://%s:%d %s:%s in %v (attempt %d/%d, %s): %v",
+			item.Type, item.Target, item.Port, item.Username, item.Password,
+			backoff, attempt+1, e.config.MaxRetries+1, result.FailureKind, result.Error)
+
+		// 中断时不记录结果，断点续传时重新尝试
+		if !sleepContext(process.ctx, backoff) {
+			return
+		}
 	}
 
-	// 执行爆破
-	startTime := time.Now()
-	result := e.executeItem(item)
-	result.ResponseTime = time.Since(startTime)
+	// 接受任意密码的目标不报告认证成功，验证没有结论时仍报告成功并标记为未验证，避免网络抖动丢失凭据
+	if result.Success && e.config.VerifySuccess {
+		switch verdict, probe := e.verifySuccess(item, process); verdict {
+		case verifyAcceptAny:
+			result.Fail(FailureAcceptAny, errors.New("target also accepts a random password"))
+		case verifyUnknown:
+			gologger.Warning().Msgf("Could not verify %s:%s on target %s, random password check ended with %s",
+				item.Username, item.Password, process.Target, probe.FailureKind)
+			result.Unverified = true
+			result.SetExtra("verify_failure", probe.FailureKind.String())
+		}
+	}
+
+	// 目标被放弃或引擎停止导致的取消不记录结果
+	if result.FailureKind == FailureCanceled && process.ctx.Err() != nil {
+		return
+	}
 
 	// 更新计数
 	atomic.AddInt32(&process.Count, 1)
+	process.complete(result)
+	process.statistics.record(result, time.Now())
 
 	// 更新全局进度
 	atomic.AddInt64(&e.processedItems, 1)
@@ -256,12 +738,22 @@ func (e *Engine) processItem(item *BruteItem, process *targetProcess, itemWg *sy
 		atomic.AddInt64(&e.successItems, 1)
 	} else {
 		atomic.AddInt64(&e.failedItems, 1)
+		e.countFailure(result.FailureKind)
 	}
+	e.recordMetrics(process.serviceType, result)
 
 	// 调用结果回调
 	if e.resultCallback != nil {
 		e.resultCallback(result)
 	}
+	e.publish(Event{Type: EventAttemptCompleted, Target: process.Target, Result: result})
+	if result.Success {
+		e.publish(Event{Type: EventCredentialFound, Target: process.Target, Result: result})
+	}
+
+	// 更新熔断状态和用户排除状态
+	e.updateCircuit(process, result)
+	e.updateElimination(process, result)
 
 	// 如果成功且配置为成功后停止，则停止处理
 	if result.Success && e.config.OkToStop {
@@ -274,7 +766,7 @@ func (e *Engine) processItem(item *BruteItem, process *targetProcess, itemWg *sy
 }
 
 // executeItem 执行单个爆破项
-func (e *Engine) executeItem(item *BruteItem) *BruteResult {
+func (e *Engine) executeItem(item *BruteItem, process *targetProcess) *BruteResult {
 	result := &BruteResult{
 		Item:    item,
 		Success: false,
@@ -286,13 +778,16 @@ func (e *Engine) executeItem(item *BruteItem) *BruteResult {
 	}
 
 	// 否则使用内置的协议处理器
-	handler, exists := GetProtocolHandler(item.Type)
+	handler, exists := GetProtocol(item.Type)
 	if !exists {
 		gologger.Error().Msgf("Unsupported protocol: %s", item.Type)
-		result.Error = fmt.Errorf("unsupported protocol: %s", item.Type)
-		return result
+		return result.Fail(FailureUnsupported, fmt.Errorf("unsupported protocol: %s", item.Type))
+	}
+	// 启用会话复用时在已建立的会话上连续尝试
+	if protocol, ok := handler.(SessionProtocol); ok && e.config.sessionReuse(item.Type) {
+		return e.executeWithSession(item, process, protocol)
 	}
-	return handler(item)
+	return handler.Authenticate(item)
 }
 
 // validateConfig 验证配置
@@ -309,6 +804,72 @@ func validateConfig(config *Config) error {
 	if config.MinDelay < 0 {
 		return fmt.Errorf("min delay cannot be negative, got: %v", config.MinDelay)
 	}
+	if config.FinishingThreshold < 0 {
+		return fmt.Errorf("finishing threshold cannot be negative, got: %d", config.FinishingThreshold)
+	}
+	if config.MaxRetries < 0 {
+		return fmt.Errorf("max retries cannot be negative, got: %d", config.MaxRetries)
+	}
+	if config.PreCheck && config.PreCheckTimeout <= 0 {
+		return fmt.Errorf("pre-check timeout must be positive, got: %v", config.PreCheckTimeout)
+	}
+	if config.PreCheck && config.PreCheckConcurrent <= 0 {
+		return fmt.Errorf("pre-check concurrent must be positive, got: %d", config.PreCheckConcurrent)
+	}
+	switch config.Strategy {
+	case StrategyStandard:
+	case StrategySpray:
+		if config.SprayWindow < 0 {
+			return fmt.Errorf("spray window cannot be negative, got: %v", config.SprayWindow)
+		}
+		if config.SprayPerRound <= 0 {
+			return fmt.Errorf("spray passwords per round must be positive, got: %d", config.SprayPerRound)
+		}
+	default:
+		return fmt.Errorf("unknown strategy: %s", config.Strategy)
+	}
+	if config.MaxSessionAttempts < 0 {
+		return fmt.Errorf("max session attempts cannot be negative, got: %d", config.MaxSessionAttempts)
+	}
+	if err := validateSessionReuse(config.SessionReuse); err != nil {
+		return err
+	}
+	if config.MaxActiveTargets < 0 {
+		return fmt.Errorf("max active targets cannot be negative, got: %d", config.MaxActiveTargets)
+	}
+	switch config.TargetOrder {
+	case "", TargetOrderInput, TargetOrderShuffle, TargetOrderSubnet:
+	default:
+		return fmt.Errorf("unknown target order: %s", config.TargetOrder)
+	}
+	for _, layer := range []struct {
+		name  string
+		limit RateLimit
+	}{
+		{"global", config.GlobalRateLimit},
+		{"host", config.HostRateLimit},
+		{"subnet", config.SubnetRateLimit},
+		{"protocol", config.ProtocolRateLimit},
+	} {
+		if err := layer.limit.validate(); err != nil {
+			return fmt.Errorf("invalid %s rate limit: %w", layer.name, err)
+		}
+	}
+	if config.Adaptive {
+		if config.AdaptiveMinConcurrent <= 0 || config.AdaptiveMinConcurrent > config.TaskConcurrent {
+			return fmt.Errorf("adaptive min concurrent must be between 1 and task concurrent (%d), got: %d",
+				config.TaskConcurrent, config.AdaptiveMinConcurrent)
+		}
+		if config.AdaptiveWindow <= 0 {
+			return fmt.Errorf("adaptive window must be positive, got: %d", config.AdaptiveWindow)
+		}
+		if config.AdaptiveThreshold <= 0 || config.AdaptiveThreshold > 1 {
+			return fmt.Errorf("adaptive threshold must be in (0, 1], got: %v", config.AdaptiveThreshold)
+		}
+		if config.AdaptiveMaxDelay < 0 {
+			return fmt.Errorf("adaptive max delay cannot be negative, got: %v", config.AdaptiveMaxDelay)
+		}
+	}
 	if config.MaxDelay > 0 && config.MinDelay > config.MaxDelay {
 		return fmt.Errorf("min delay (%v) cannot be greater than max delay (%v)", config.MinDelay, config.MaxDelay)
 	}
@@ -324,24 +885,46 @@ func validateConfig(config *Config) error {
 	return nil
 }
 
-// loadDictionaries 加载字典
+// activeTargetLimit 返回同时处理的目标数上限，未单独设置时与目标并发数相同
+func activeTargetLimit(config *Config, targetConcurrent int) int {
+	if config.MaxActiveTargets > 0 {
+		return config.MaxActiveTargets
+	}
+	return targetConcurrent
+}
+
+// loadDictionaries 检查字典配置
+// 字典文件不会被载入内存，而是在生成任务时按需流式读取
 func loadDictionaries(config *Config) error {
-	// 加载用户字典文件
+	// 检查用户字典文件
 	if config.UserDictFile != "" {
-		userDict, err := loadDictFromFile(config.UserDictFile)
-		if err != nil {
+		if _, err := os.Stat(config.UserDictFile); err != nil {
 			return fmt.Errorf("failed to load user dictionary: %w", err)
 		}
-		config.UserDict = append(config.UserDict, userDict...)
 	}
 
-	// 加载密码字典文件
+	// 检查密码字典文件
 	if config.PassDictFile != "" {
-		passDict, err := loadDictFromFile(config.PassDictFile)
-		if err != nil {
+		if _, err := os.Stat(config.PassDictFile); err != nil {
 			return fmt.Errorf("failed to load password dictionary: %w", err)
 		}
-		config.PassDict = append(config.PassDict, passDict...)
+	}
+
+	// 解析密码变形规则
+	config.passRules = nil
+	for _, text := range config.Rules {
+		rule, err := ParseRule(text)
+		if err != nil {
+			return fmt.Errorf("failed to parse rule: %w", err)
+		}
+		config.passRules = append(config.passRules, rule)
+	}
+	if config.RulesFile != "" {
+		rules, err := LoadRules(config.RulesFile)
+		if err != nil {
+			return fmt.Errorf("failed to load rules: %w", err)
+		}
+		config.passRules = append(config.passRules, rules...)
 	}
 
 	// 去重
@@ -351,28 +934,39 @@ func loadDictionaries(config *Config) error {
 	return nil
 }
 
-// loadDictFromFile 从文件加载字典
-func loadDictFromFile(filename string) ([]string, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
+// userWordlist 返回由内存字典和字典文件组成的用户名字典
+func (c *Config) userWordlist() Wordlist {
+	return buildWordlist(c.UserDict, c.UserDictFile, c.SkipEmptyUsername)
+}
 
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line != "" && !strings.HasPrefix(line, "#") {
-			lines = append(lines, line)
-		}
+// passWordlist 返回由内存字典和字典文件组成的密码字典，设置了变形规则时在遍历时逐个生成变形结果
+func (c *Config) passWordlist() Wordlist {
+	passwords := buildWordlist(c.PassDict, c.PassDictFile, c.SkipEmptyPassword)
+	if len(c.passRules) > 0 {
+		return NewRuleWordlist(passwords, c.passRules)
 	}
+	return passwords
+}
 
-	return lines, scanner.Err()
+// buildWordlist 组合内存字典和字典文件，字典文件中不会出现空行
+// 字典文件中已在内存字典中的词在遍历时跳过，文件内重复的行不去重，避免每次遍历都记录整个字典
+func buildWordlist(words []string, filename string, skipEmpty bool) Wordlist {
+	if skipEmpty {
+		words = lo.Filter(words, func(word string, _ int) bool {
+			return word != ""
+		})
+	}
+	if filename == "" {
+		return SliceWordlist(words)
+	}
+	words = lo.Uniq(words)
+	return ChainWordlists(SliceWordlist(words), ExcludeWordlist(NewFileWordlist(filename), words))
 }
 
 // GetTargetCount 获取目标数量
 func (e *Engine) GetTargetCount() int {
+	e.targetsMutex.RLock()
+	defer e.targetsMutex.RUnlock()
 	return e.targets.Len()
 }
 
@@ -387,35 +981,36 @@ func (e *Engine) GetProcessedCount() int32 {
 	return total
 }
 
-// UpdateRateLimit 动态更新限流器设置
+// UpdateRateLimit 动态更新全局限流器设置，每隔 minDelay 允许一个请求
 func (e *Engine) UpdateRateLimit(minDelay time.Duration, burstSize int) {
 	if minDelay <= 0 || burstSize <= 0 {
 		gologger.Warning().Msg("Invalid rate limit parameters, ignoring update")
 		return
 	}
 
-	// 创建新的限流器
-	newLimiter := rate.NewLimiter(rate.Every(minDelay), burstSize)
-	e.limiter = newLimiter
+	// 原地更新，正在等待的工作协程会使用新的速率
+	e.limiters.fromDelay.Store(false)
+	e.limiters.global.SetLimit(rate.Every(minDelay))
+	e.limiters.global.SetBurst(burstSize)
 	gologger.Info().Msgf("Rate limiter updated: delay=%v, burst=%d", minDelay, burstSize)
 }
 
-// GetRateLimitStatus 获取限流器状态
+// GetRateLimitStatus 获取全局限流器状态
 func (e *Engine) GetRateLimitStatus() (limit rate.Limit, burst int) {
-	return e.limiter.Limit(), e.limiter.Burst()
+	return e.limiters.global.Limit(), e.limiters.global.Burst()
 }
 
-// GetConcurrencyStatus 获取并发状态
+// GetConcurrencyStatus 获取并发状态，目标级别的容量为自适应控制调整后的当前值
 func (e *Engine) GetConcurrencyStatus() (globalUsed, globalTotal, targetUsed, targetTotal int) {
-	globalTotal = cap(e.globalSem)
-	globalUsed = len(e.globalSem)
+	globalUsed, globalTotal = e.globalSem.status()
 
 	// 统计所有目标的并发使用情况
 	var totalTargetUsed, totalTargetCap int
 	e.processes.Range(func(key, value interface{}) bool {
 		process := value.(*targetProcess)
-		totalTargetUsed += len(process.semaphore)
-		totalTargetCap += cap(process.semaphore)
+		used, limit := process.semaphore.status()
+		totalTargetUsed += used
+		totalTargetCap += limit
 		return true
 	})
 
@@ -467,7 +1062,8 @@ func (e *Engine) startProgressTicker() {
 func (e *Engine) isActive() bool {
 	total := atomic.LoadInt64(&e.totalItems)
 	processed := atomic.LoadInt64(&e.processedItems)
-	return processed < total
+	skipped := atomic.LoadInt64(&e.skippedItems)
+	return processed+skipped < total
 }
 
 // stopProgressTicker 停止进度打印定时器
@@ -487,6 +1083,7 @@ func (e *Engine) printProgress() {
 	processed := atomic.LoadInt64(&e.processedItems)
 	success := atomic.LoadInt64(&e.successItems)
 	failed := atomic.LoadInt64(&e.failedItems)
+	skipped := atomic.LoadInt64(&e.skippedItems)
 
 	if total == 0 {
 		return // 避免除零错误
@@ -495,13 +1092,13 @@ func (e *Engine) printProgress() {
 	elapsed := time.Since(e.startTime)
 	rate := float64(processed) / elapsed.Seconds()
 
-	// 计算进度百分比
-	percentage := float64(processed) / float64(total) * 100
+	// 计算进度百分比，跳过的任务视为已完成
+	percentage := float64(processed+skipped) / float64(total) * 100
 
 	// 估算剩余时间
 	var eta string
-	if rate > 0 && processed < total {
-		remaining := total - processed
+	if rate > 0 && processed+skipped < total {
+		remaining := total - processed - skipped
 		etaSeconds := float64(remaining) / rate
 		eta = time.Duration(etaSeconds * float64(time.Second)).Truncate(time.Second).String()
 	} else {
@@ -510,9 +1107,25 @@ func (e *Engine) printProgress() {
 
 	// 获取并发状态
 	globalUsed, globalTotal, targetUsed, targetTotal := e.GetConcurrencyStatus()
+	activeTargets, queuedTargets := e.GetTargetQueueStatus()
 
-	gologger.Info().Msgf("Progress: %d/%d (%.1f%%) | Success: %d | Failed: %d | Rate: %.2f/s | ETA: %s | Concurrency: G=%d/%d T=%d/%d",
-		processed, total, percentage, success, failed, rate, eta, globalUsed, globalTotal, targetUsed, targetTotal)
+	// 密码喷洒模式下显示距离下一轮的时间
+	var nextRound string
+	if remaining, ok := e.NextSprayRound(); ok {
+		nextRound = fmt.Sprintf(" | Next Round: %s", remaining.Truncate(time.Second))
+	}
+
+	// 运行时可调整的设置
+	minDelay, maxDelay := e.GetDelay()
+	limit, burst := e.GetRateLimitStatus()
+	var status string
+	if e.IsPaused() {
+		status = " | PAUSED"
+	}
+
+	gologger.Info().Msgf("Progress: %d/%d (%.1f%%) | Success: %d | Failed: %d | Skipped: %d | Rate: %.2f/s | ETA: %s | Targets: %d active, %d queued | Concurrency: G=%d/%d T=%d/%d | Delay: %v-%v | Limit: %s%s%s",
+		processed, total, percentage, success, failed, skipped, rate, eta, activeTargets, queuedTargets,
+		globalUsed, globalTotal, targetUsed, targetTotal, minDelay, maxDelay, formatRateLimit(limit, burst), nextRound, status)
 }
 
 // printFinalStats 打印最终统计信息
@@ -526,15 +1139,66 @@ func (e *Engine) printFinalStats() {
 	avgRate := float64(processed) / elapsed.Seconds()
 
 	gologger.Info().Msgf("=== Brute Force Completed ===")
-	gologger.Info().Msgf("Total Tasks: %d | Processed: %d | Success: %d | Failed: %d",
-		total, processed, success, failed)
-	gologger.Info().Msgf("Time Elapsed: %v | Average Rate: %.2f items/sec",
-		elapsed.Truncate(time.Millisecond), avgRate)
+	gologger.Info().Msgf("Total Tasks: %d | Processed: %d | Success: %d | Failed: %d | Skipped: %d",
+		total, processed, success, failed, atomic.LoadInt64(&e.skippedItems))
+	gologger.Info().Msgf("Time Elapsed: %v | Average Rate: %.2f items/sec | Retries: %d",
+		elapsed.Truncate(time.Millisecond), avgRate, atomic.LoadInt64(&e.retriedItems))
 
 	if success > 0 {
 		successRate := float64(success) / float64(processed) * 100
 		gologger.Info().Msgf("Success Rate: %.2f%%", successRate)
 	}
+
+	// 未授权访问
+	if unauth := e.GetUnauthTargets(); unauth > 0 {
+		gologger.Info().Msgf("Unauthenticated access: %d targets", unauth)
+	}
+
+	// 会话复用
+	if opened, reused := e.GetSessionStats(); opened > 0 {
+		gologger.Info().Msgf("Sessions: %d opened | %d attempts on reused sessions", opened, reused)
+	}
+
+	// 按失败类型统计
+	if failures := e.formatFailureStats(); failures != "" {
+		gologger.Info().Msgf("Failures: %s", failures)
+	}
+
+	// 被放弃的目标
+	if abandoned := e.GetAbandonedTargets(); len(abandoned) > 0 {
+		gologger.Info().Msgf("Abandoned Targets: %d", len(abandoned))
+		for target, reason := range abandoned {
+			gologger.Info().Msgf("  %s: %s", target, reason)
+		}
+	}
+}
+
+// countFailure 记录一次指定类型的失败
+func (e *Engine) countFailure(kind FailureKind) {
+	counter, _ := e.failureCounts.LoadOrStore(kind, new(int64))
+	atomic.AddInt64(counter.(*int64), 1)
+}
+
+// GetFailureStats 获取各失败类型的次数
+func (e *Engine) GetFailureStats() map[FailureKind]int64 {
+	stats := make(map[FailureKind]int64)
+	e.failureCounts.Range(func(key, value interface{}) bool {
+		stats[key.(FailureKind)] = atomic.LoadInt64(value.(*int64))
+		return true
+	})
+	return stats
+}
+
+// formatFailureStats 按固定顺序格式化失败类型统计
+func (e *Engine) formatFailureStats() string {
+	stats := e.GetFailureStats()
+	var parts []string
+	for _, kind := range FailureKinds() {
+		if count := stats[kind]; count > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", kind, count))
+		}
+	}
+	return strings.Join(parts, " ")
 }
 
 // GetProgressStats 获取详细的进度统计信息
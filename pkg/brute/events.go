@@ -0,0 +1,148 @@
+package brute
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EventType 引擎事件类型
+type EventType int
+
+const (
+	EventTargetStarted    EventType = iota + 1 // 目标开始调度
+	EventAttemptCompleted                      // 一次尝试得出结果（包含重试）
+	EventCredentialFound                       // 发现有效凭据
+	EventUnauthenticated                       // 未授权探测发现目标无需认证即可访问
+	EventTargetSkipped                         // 目标被跳过或放弃，Reason 为原因
+	EventTargetFinished                        // 目标处理结束
+	EventEngineDone                            // 所有目标处理完毕，之后不再有事件
+)
+
+// eventTypeNames 事件类型的名称，用于日志和 JSON 输出
+var eventTypeNames = map[EventType]string{
+	EventTargetStarted:    "target_started",
+	EventAttemptCompleted: "attempt_completed",
+	EventCredentialFound:  "credential_found",
+	EventUnauthenticated:  "unauthenticated",
+	EventTargetSkipped:    "target_skipped",
+	EventTargetFinished:   "target_finished",
+	EventEngineDone:       "engine_done",
+}
+
+// String 返回事件类型的名称
+func (t EventType) String() string {
+	if name, ok := eventTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("event(%d)", int(t))
+}
+
+// MarshalJSON 以名称形式输出事件类型
+func (t EventType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// Event 引擎事件
+type Event struct {
+	Type   EventType    `json:"type"`
+	Time   time.Time    `json:"time"`
+	Target string       `json:"target,omitempty"` // 目标标识，格式为 type:host:port，EngineDone 时为空
+	Result *BruteResult `json:"result,omitempty"` // AttemptCompleted、CredentialFound 和 Unauthenticated 的结果
+	Reason string       `json:"reason,omitempty"` // TargetSkipped 的原因，目标被放弃时 TargetFinished 也会携带
+}
+
+// Subscription 事件订阅
+type Subscription struct {
+	events chan Event
+	types  map[EventType]bool // 订阅的事件类型，为空时订阅全部
+	done   chan struct{}
+	once   sync.Once
+	engine *Engine
+}
+
+// Events 返回事件通道，EngineDone 之后或取消订阅后通道关闭
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Unsubscribe 取消订阅，阻塞在该订阅上的引擎会立即继续
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		close(s.done)
+		s.engine.removeSubscription(s)
+	})
+}
+
+// wants 检查是否订阅了指定的事件类型
+func (s *Subscription) wants(eventType EventType) bool {
+	return len(s.types) == 0 || s.types[eventType]
+}
+
+// Subscribe 订阅引擎事件，buffer 为通道缓冲大小，types 为空时订阅全部事件
+// 事件不会被丢弃：缓冲区满时引擎会等待订阅者读取，因此订阅者必须持续读取通道或取消订阅
+// 只关心部分事件时应指定 types，避免大量的 AttemptCompleted 事件拖慢引擎
+func (e *Engine) Subscribe(buffer int, types ...EventType) *Subscription {
+	sub := &Subscription{
+		events: make(chan Event, max(buffer, 0)),
+		types:  make(map[EventType]bool, len(types)),
+		done:   make(chan struct{}),
+		engine: e,
+	}
+	for _, eventType := range types {
+		sub.types[eventType] = true
+	}
+
+	e.subsMutex.Lock()
+	defer e.subsMutex.Unlock()
+	if e.eventsClosed {
+		close(sub.events)
+		return sub
+	}
+	e.subscriptions = append(e.subscriptions, sub)
+	return sub
+}
+
+// removeSubscription 移除订阅并关闭其事件通道
+func (e *Engine) removeSubscription(sub *Subscription) {
+	e.subsMutex.Lock()
+	defer e.subsMutex.Unlock()
+	for i, s := range e.subscriptions {
+		if s == sub {
+			e.subscriptions = append(e.subscriptions[:i], e.subscriptions[i+1:]...)
+			close(sub.events)
+			return
+		}
+	}
+}
+
+// publish 向所有订阅者发送事件，订阅者的缓冲区满时等待
+func (e *Engine) publish(event Event) {
+	event.Time = time.Now()
+
+	e.subsMutex.RLock()
+	defer e.subsMutex.RUnlock()
+	for _, sub := range e.subscriptions {
+		if !sub.wants(event.Type) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		case <-sub.done:
+		}
+	}
+}
+
+// publishDone 发送 EngineDone 事件并关闭所有订阅
+func (e *Engine) publishDone() {
+	e.publish(Event{Type: EventEngineDone})
+
+	e.subsMutex.Lock()
+	defer e.subsMutex.Unlock()
+	e.eventsClosed = true
+	for _, sub := range e.subscriptions {
+		close(sub.events)
+	}
+	e.subscriptions = nil
+}
@@ -0,0 +1,258 @@
+package brute
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// FailureKind 爆破失败的类型
+type FailureKind int
+
+const (
+	FailureNone          FailureKind = iota // 未失败
+	FailureAuthRejected                     // 认证被拒绝，即用户名或密码错误
+	FailureConnRefused                      // 连接被拒绝，端口未开放
+	FailureUnreachable                      // 主机或网络不可达，域名无法解析
+	FailureConnReset                        // 连接被重置或意外关闭
+	FailureTimeout                          // 连接或读写超时
+	FailureTLS                              // TLS 握手失败
+	FailureProtocolError                    // 服务响应不符合协议预期
+	FailureLocked                           // 账号被锁定、禁用，或来源地址被服务封禁
+	FailureUnsupported                      // 凭据形式或认证方式不被支持
+	FailureAcceptAny                        // 服务接受随机密码，认证结果不可信，可能是蜜罐或配置错误
+	FailureCanceled                         // 任务被取消
+	FailureUnknown                          // 无法归类的错误
+)
+
+// failureKindNames 失败类型的名称，用于日志和 JSON 输出
+var failureKindNames = map[FailureKind]string{
+	FailureNone:          "none",
+	FailureAuthRejected:  "auth_rejected",
+	FailureConnRefused:   "conn_refused",
+	FailureUnreachable:   "unreachable",
+	FailureConnReset:     "conn_reset",
+	FailureTimeout:       "timeout",
+	FailureTLS:           "tls",
+	FailureProtocolError: "protocol_error",
+	FailureLocked:        "locked",
+	FailureUnsupported:   "unsupported",
+	FailureAcceptAny:     "accept_any",
+	FailureCanceled:      "canceled",
+	FailureUnknown:       "unknown",
+}
+
+// FailureKinds 返回所有失败类型（不含 FailureNone）
+func FailureKinds() []FailureKind {
+	kinds := make([]FailureKind, 0, len(failureKindNames)-1)
+	for kind := FailureAuthRejected; kind <= FailureUnknown; kind++ {
+		kinds = append(kinds, kind)
+	}
+	return kinds
+}
+
+// String 返回失败类型的名称
+func (k FailureKind) String() string {
+	if name, ok := failureKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("failure(%d)", int(k))
+}
+
+// MarshalJSON 以名称形式输出失败类型
+func (k FailureKind) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.String())
+}
+
+// UnmarshalJSON 从名称解析失败类型
+func (k *FailureKind) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for kind, kindName := range failureKindNames {
+		if kindName == name {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown failure kind: %s", name)
+}
+
+// MarshalText 以名称形式输出失败类型，用作 JSON 对象的键
+func (k FailureKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText 从名称解析失败类型
+func (k *FailureKind) UnmarshalText(text []byte) error {
+	for kind, kindName := range failureKindNames {
+		if kindName == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown failure kind: %s", text)
+}
+
+// Retryable 是否为值得重试的瞬时错误
+func (k FailureKind) Retryable() bool {
+	return k == FailureTimeout || k == FailureConnReset
+}
+
+// IsNetwork 是否为网络层面的失败，即没有得到服务的认证结论
+func (k FailureKind) IsNetwork() bool {
+	switch k {
+	case FailureConnRefused, FailureUnreachable, FailureConnReset, FailureTimeout, FailureTLS:
+		return true
+	}
+	return false
+}
+
+// ClassifyError 根据错误推断失败类型，无法从错误判断的认证失败需由协议处理器显式标记
+func ClassifyError(err error) FailureKind {
+	if err == nil {
+		return FailureNone
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return FailureCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, syscall.ETIMEDOUT):
+		return FailureTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return FailureConnRefused
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EHOSTDOWN):
+		return FailureUnreachable
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNABORTED), errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, net.ErrClosed):
+		return FailureConnReset
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return FailureTimeout
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return FailureUnreachable
+	}
+
+	var (
+		recordErr      tls.RecordHeaderError
+		alertErr       tls.AlertError
+		verifyErr      *tls.CertificateVerificationError
+		unknownCAErr   x509.UnknownAuthorityError
+		hostnameErr    x509.HostnameError
+		certInvalidErr x509.CertificateInvalidError
+	)
+	if errors.As(err, &recordErr) || errors.As(err, &alertErr) || errors.As(err, &verifyErr) ||
+		errors.As(err, &unknownCAErr) || errors.As(err, &hostnameErr) || errors.As(err, &certInvalidErr) {
+		return FailureTLS
+	}
+
+	// 部分协议库不会包装底层错误，只能通过错误信息判断
+	message := strings.ToLower(err.Error())
+	for _, rule := range failureKeywords {
+		for _, keyword := range rule.keywords {
+			if strings.Contains(message, keyword) {
+				return rule.kind
+			}
+		}
+	}
+	return FailureUnknown
+}
+
+// ClassifyAuthError 推断认证阶段返回的错误的失败类型
+// 连接已经建立后，除网络错误和账号锁定外，服务返回的错误均视为认证被拒绝
+func ClassifyAuthError(err error) FailureKind {
+	kind := ClassifyError(err)
+	if kind == FailureUnknown {
+		return FailureAuthRejected
+	}
+	return kind
+}
+
+// failureKeywords 错误信息关键字与失败类型的对应关系，按顺序匹配
+var failureKeywords = []struct {
+	kind     FailureKind
+	keywords []string
+}{
+	{FailureConnRefused, []string{"connection refused", "actively refused"}},
+	{FailureUnreachable, []string{"no route to host", "network is unreachable", "host is down", "no such host"}},
+	{FailureTimeout, []string{"timeout", "timed out", "deadline exceeded"}},
+	{FailureConnReset, []string{"connection reset", "broken pipe", "unexpected eof", "forcibly closed", "use of closed network connection"}},
+	{FailureTLS, []string{"tls:", "x509:", "handshake failure"}},
+	{FailureLocked, []string{"account locked", "account is locked", "locked out", "account disabled", "too many authentication failures"}},
+}
+
+// NewResult 创建一个尚未得出结论的爆破结果
+func NewResult(item *BruteItem) *BruteResult {
+	return &BruteResult{
+		Item:    item,
+		Success: false,
+	}
+}
+
+// Succeed 将结果标记为成功
+func (r *BruteResult) Succeed(banner string) *BruteResult {
+	r.Success = true
+	r.FailureKind = FailureNone
+	r.Error = nil
+	r.Banner = banner
+	return r
+}
+
+// SetExtra 记录认证成功后采集的附加信息
+func (r *BruteResult) SetExtra(key string, value interface{}) *BruteResult {
+	if r.ExtraInfo == nil {
+		r.ExtraInfo = make(map[string]interface{})
+	}
+	r.ExtraInfo[key] = value
+	return r
+}
+
+// Fail 以指定的失败类型标记结果
+func (r *BruteResult) Fail(kind FailureKind, err error) *BruteResult {
+	r.Success = false
+	r.FailureKind = kind
+	r.Error = err
+	return r
+}
+
+// AuthRejected 将结果标记为认证被拒绝
+func (r *BruteResult) AuthRejected(err error) *BruteResult {
+	return r.Fail(FailureAuthRejected, err)
+}
+
+// Unsupported 将结果标记为凭据形式不被支持，例如向只使用密码的服务提交用户名
+func (r *BruteResult) Unsupported(reason string) *BruteResult {
+	return r.Fail(FailureUnsupported, errors.New(reason))
+}
+
+// FailWithError 根据错误推断失败类型，适用于连接、握手等尚未得到认证结论的阶段
+func (r *BruteResult) FailWithError(err error) *BruteResult {
+	return r.Fail(ClassifyError(err), err)
+}
+
+// normalize 补全协议处理器没有填写的失败类型
+func (r *BruteResult) normalize() {
+	switch {
+	case r.Success:
+		r.FailureKind = FailureNone
+	case r.FailureKind != FailureNone:
+	case r.Error == nil:
+		// 没有错误的失败即服务明确拒绝了凭据
+		r.FailureKind = FailureAuthRejected
+	default:
+		r.FailureKind = ClassifyError(r.Error)
+	}
+}
@@ -0,0 +1,207 @@
+package brute
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// latencyBuckets 尝试耗时直方图的上界（秒）
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// protocolMetrics 单个协议的尝试统计
+type protocolMetrics struct {
+	outcomes sync.Map // 各结果的次数，FailureKind -> *int64，成功记为 FailureNone
+	buckets  []int64  // 落入各区间的次数，最后一个为超过最大上界的次数
+	count    int64    // 尝试次数
+	sumNanos int64    // 总耗时（纳秒）
+}
+
+// newProtocolMetrics 创建协议统计
+func newProtocolMetrics() *protocolMetrics {
+	return &protocolMetrics{buckets: make([]int64, len(latencyBuckets)+1)}
+}
+
+// observe 记录一次尝试的结果和耗时
+func (m *protocolMetrics) observe(result *BruteResult) {
+	counter, _ := m.outcomes.LoadOrStore(result.FailureKind, new(int64))
+	atomic.AddInt64(counter.(*int64), 1)
+
+	seconds := result.ResponseTime.Seconds()
+	bucket := sort.SearchFloat64s(latencyBuckets, seconds)
+	atomic.AddInt64(&m.buckets[bucket], 1)
+	atomic.AddInt64(&m.count, 1)
+	atomic.AddInt64(&m.sumNanos, int64(result.ResponseTime))
+}
+
+// recordMetrics 记录尝试结果，按协议统计
+func (e *Engine) recordMetrics(protocol string, result *BruteResult) {
+	metrics, ok := e.protocolMetrics.Load(protocol)
+	if !ok {
+		metrics, _ = e.protocolMetrics.LoadOrStore(protocol, newProtocolMetrics())
+	}
+	metrics.(*protocolMetrics).observe(result)
+}
+
+// MetricsHandler 返回以 Prometheus 文本格式导出引擎指标的 HTTP 处理器
+func (e *Engine) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		buffered := bufio.NewWriter(w)
+		e.WriteMetrics(buffered)
+		buffered.Flush()
+	})
+}
+
+// WriteMetrics 以 Prometheus 文本格式写出引擎指标
+func (e *Engine) WriteMetrics(w io.Writer) {
+	writeMetric(w, "xcrack_tasks", "gauge", "Total number of brute force tasks.", "", atomic.LoadInt64(&e.totalItems))
+	writeMetric(w, "xcrack_tasks_processed_total", "counter", "Number of tasks with a recorded result.", "", atomic.LoadInt64(&e.processedItems))
+	writeMetric(w, "xcrack_tasks_success_total", "counter", "Number of tasks that found a valid credential.", "", atomic.LoadInt64(&e.successItems))
+	writeMetric(w, "xcrack_tasks_failed_total", "counter", "Number of tasks that failed.", "", atomic.LoadInt64(&e.failedItems))
+	writeMetric(w, "xcrack_tasks_skipped_total", "counter", "Number of tasks skipped for abandoned targets or eliminated users.", "", atomic.LoadInt64(&e.skippedItems))
+	writeMetric(w, "xcrack_retries_total", "counter", "Number of retries after transient failures.", "", atomic.LoadInt64(&e.retriedItems))
+	writeMetric(w, "xcrack_unauth_targets_total", "counter", "Number of targets that allow access without authentication.", "", e.GetUnauthTargets())
+	sessionsOpened, sessionReuses := e.GetSessionStats()
+	writeMetric(w, "xcrack_sessions_opened_total", "counter", "Number of sessions opened with session reuse enabled.", "", sessionsOpened)
+	writeMetric(w, "xcrack_session_reuses_total", "counter", "Number of attempts made over an already open session.", "", sessionReuses)
+	writeMetric(w, "xcrack_targets_abandoned", "gauge", "Number of abandoned targets.", "", len(e.GetAbandonedTargets()))
+
+	activeTargets, queuedTargets := e.GetTargetQueueStatus()
+	writeHeader(w, "xcrack_targets", "gauge", "Targets holding a worker slot or waiting in the queue.")
+	writeSample(w, "xcrack_targets", labels("state", "active"), activeTargets)
+	writeSample(w, "xcrack_targets", labels("state", "queued"), queuedTargets)
+
+	paused := 0
+	if e.IsPaused() {
+		paused = 1
+	}
+	writeMetric(w, "xcrack_paused", "gauge", "Whether scheduling is paused.", "", paused)
+
+	// 并发状态
+	globalUsed, globalTotal, targetUsed, targetTotal := e.GetConcurrencyStatus()
+	writeHeader(w, "xcrack_concurrency_in_use", "gauge", "Occupied concurrency slots.")
+	writeSample(w, "xcrack_concurrency_in_use", labels("scope", "global"), globalUsed)
+	writeSample(w, "xcrack_concurrency_in_use", labels("scope", "target"), targetUsed)
+	writeHeader(w, "xcrack_concurrency_limit", "gauge", "Current concurrency limits, per-target limits are summed.")
+	writeSample(w, "xcrack_concurrency_limit", labels("scope", "global"), globalTotal)
+	writeSample(w, "xcrack_concurrency_limit", labels("scope", "target"), targetTotal)
+
+	// 按协议和失败类型统计的尝试
+	protocols := make(map[string]*protocolMetrics)
+	e.protocolMetrics.Range(func(key, value interface{}) bool {
+		protocols[key.(string)] = value.(*protocolMetrics)
+		return true
+	})
+	names := make([]string, 0, len(protocols))
+	for name := range protocols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	writeHeader(w, "xcrack_attempts_total", "counter", "Attempts by protocol, result and failure kind.")
+	for _, name := range names {
+		metrics := protocols[name]
+		for _, kind := range append([]FailureKind{FailureNone}, FailureKinds()...) {
+			counter, ok := metrics.outcomes.Load(kind)
+			if !ok {
+				continue
+			}
+			result := "failure"
+			if kind == FailureNone {
+				result = "success"
+			}
+			writeSample(w, "xcrack_attempts_total",
+				labels("protocol", name, "result", result, "failure_kind", kind.String()),
+				atomic.LoadInt64(counter.(*int64)))
+		}
+	}
+
+	writeHeader(w, "xcrack_attempt_duration_seconds", "histogram", "Duration of the final attempt of each task by protocol.")
+	for _, name := range names {
+		metrics := protocols[name]
+		var cumulative int64
+		for i, bound := range latencyBuckets {
+			cumulative += atomic.LoadInt64(&metrics.buckets[i])
+			writeSample(w, "xcrack_attempt_duration_seconds_bucket",
+				labels("protocol", name, "le", strconv.FormatFloat(bound, 'g', -1, 64)), cumulative)
+		}
+		count := atomic.LoadInt64(&metrics.count)
+		writeSample(w, "xcrack_attempt_duration_seconds_bucket", labels("protocol", name, "le", "+Inf"), count)
+		writeSample(w, "xcrack_attempt_duration_seconds_sum", labels("protocol", name),
+			time.Duration(atomic.LoadInt64(&metrics.sumNanos)).Seconds())
+		writeSample(w, "xcrack_attempt_duration_seconds_count", labels("protocol", name), count)
+	}
+}
+
+// writeMetric 写出只有一个样本的指标
+func writeMetric(w io.Writer, name, metricType, help, labelSet string, value interface{}) {
+	writeHeader(w, name, metricType, help)
+	writeSample(w, name, labelSet, value)
+}
+
+// writeHeader 写出指标的 HELP 和 TYPE 行
+func writeHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// writeSample 写出一个样本
+func writeSample(w io.Writer, name, labelSet string, value interface{}) {
+	fmt.Fprintf(w, "%s%s %v\n", name, labelSet, value)
+}
+
+// labels 格式化标签，参数为交替的标签名和标签值
+func labels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+`="`+labelEscaper.Replace(pairs[i+1])+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// labelEscaper 按 Prometheus 文本格式转义标签值，只转义反斜杠、双引号和换行，其余字符按 UTF-8 原样输出
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// startMetricsServer 在配置的地址上启动指标 HTTP 服务
+func (e *Engine) startMetricsServer() error {
+	listener, err := net.Listen("tcp", e.config.MetricsListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e.MetricsHandler())
+	e.metricsServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := e.metricsServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			gologger.Error().Msgf("Metrics server failed: %v", err)
+		}
+	}()
+
+	gologger.Info().Msgf("Serving metrics on http://%s/metrics", listener.Addr())
+	return nil
+}
+
+// stopMetricsServer 停止指标 HTTP 服务
+func (e *Engine) stopMetricsServer() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.metricsServer.Shutdown(ctx); err != nil {
+		gologger.Warning().Msgf("Failed to stop metrics server: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package brute
+
+import (
+	"github.com/samber/lo"
+)
+
+// CredentialProvider 按协议返回成对尝试的凭据，例如设备的默认账号密码
+// 返回空列表时该协议的目标仍使用用户名和密码字典
+type CredentialProvider func(protocol string) []Credential
+
+// pairSource 按顺序为每组凭据生成一个任务项，用户名和密码不做交叉组合
+type pairSource struct {
+	template BruteItem
+	pairs    []Credential
+	rounds   []int // 密码喷洒模式下每组凭据所在的轮次
+	total    int64
+}
+
+// newPairSource 创建成对凭据任务源，凭据按认证模型调整后去重
+// 只需要密码的协议忽略用户名，没有认证的协议不生成任务
+// 空用户名和空密码是设备默认凭据的一部分（如 root 空密码），不受 SkipEmptyUsername 和 SkipEmptyPassword 影响
+// 密码喷洒模式下相同密码的凭据归为一组，每 perRound 个密码为一轮
+func (c *Config) newPairSource(template BruteItem, model AuthModel, pairs []Credential) *pairSource {
+	if model == AuthNone {
+		pairs = nil
+	}
+	if model == AuthPasswordOnly {
+		pairs = lo.Map(pairs, func(pair Credential, _ int) Credential {
+			return Credential{Password: pair.Password}
+		})
+	}
+	pairs = lo.Uniq(pairs)
+
+	source := &pairSource{template: template, pairs: pairs, total: int64(len(pairs))}
+	if c.Strategy == StrategySpray {
+		perRound := max(c.SprayPerRound, 1)
+		order := lo.Uniq(lo.Map(pairs, func(pair Credential, _ int) string { return pair.Password }))
+		index := make(map[string]int, len(order))
+		for i, password := range order {
+			index[password] = i
+		}
+		source.pairs = make([]Credential, 0, len(pairs))
+		for _, password := range order {
+			source.pairs = append(source.pairs, lo.Filter(pairs, func(pair Credential, _ int) bool {
+				return pair.Password == password
+			})...)
+		}
+		source.rounds = lo.Map(source.pairs, func(pair Credential, _ int) int {
+			return index[pair.Password] / perRound
+		})
+	}
+	return source
+}
+
+func (s *pairSource) Next() (*BruteItem, bool) {
+	if len(s.pairs) == 0 {
+		return nil, false
+	}
+	item := s.template
+	item.Username = s.pairs[0].Username
+	item.Password = s.pairs[0].Password
+	if len(s.rounds) > 0 {
+		item.Round = s.rounds[0]
+		s.rounds = s.rounds[1:]
+	}
+	item.Extra = copyExtra(s.template.Extra)
+	s.pairs = s.pairs[1:]
+	return &item, true
+}
+
+func (s *pairSource) Total() int64 {
+	return s.total
+}
+
+func (s *pairSource) Close() error {
+	s.pairs = nil
+	s.rounds = nil
+	return nil
+}
@@ -0,0 +1,35 @@
+package brute
+
+import (
+	"fmt"
+
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
+)
+
+// probeNetwork 返回协议存活探测使用的网络类型
+func probeNetwork(serviceType string) string {
+	return string(protocolTransport(serviceType))
+}
+
+// precheckTarget 在调度凭据之前探测目标是否可达，返回 false 表示不再处理该目标
+func (e *Engine) precheckTarget(process *targetProcess) bool {
+	select {
+	case e.probeSem <- struct{}{}:
+	case <-process.ctx.Done():
+		return false
+	}
+	network := probeNetwork(process.serviceType)
+	err := utils.ProbePort(process.ctx, network, process.host, process.port, e.config.PreCheckTimeout)
+	<-e.probeSem
+
+	if err == nil {
+		return true
+	}
+	// 中断导致的探测失败不代表目标不可达
+	if process.ctx.Err() != nil {
+		return false
+	}
+
+	e.abandonTarget(process, fmt.Sprintf("unreachable (%s): %v", ClassifyError(err), err))
+	return false
+}
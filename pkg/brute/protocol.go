@@ -0,0 +1,181 @@
+package brute
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
+	"github.com/projectdiscovery/gologger"
+)
+
+// Transport 协议使用的传输层
+type Transport string
+
+const (
+	TransportTCP Transport = "tcp"
+	TransportUDP Transport = "udp"
+)
+
+// ProtocolInfo 协议的元数据
+type ProtocolInfo struct {
+	Name         string    `json:"name"`                 // 协议名，即目标的服务类型
+	Aliases      []string  `json:"aliases,omitempty"`    // 别名，例如 postgres 之于 postgresql
+	DefaultPorts []int     `json:"default_ports"`        // 默认端口，未指定端口时使用
+	Transport    Transport `json:"transport"`            // 传输层，存活探测时使用，为空时为 TCP
+	AuthModel    AuthModel `json:"auth_model"`           // 认证模型，决定生成哪些用户名和密码组合
+	TLS          bool      `json:"tls"`                  // 是否支持 TLS（包括 STARTTLS）
+	ExtraKeys    []string  `json:"extra_keys,omitempty"` // 支持的 BruteItem.Extra 键
+}
+
+// ProtocolHandler 协议处理器
+type ProtocolHandler interface {
+	// Info 返回协议的元数据
+	Info() ProtocolInfo
+	// Authenticate 使用任务项中的凭据认证一次
+	Authenticate(item *BruteItem) *BruteResult
+}
+
+// callbackHandler 将 BruteCallback 适配为 ProtocolHandler
+type callbackHandler struct {
+	info     ProtocolInfo
+	callback BruteCallback
+}
+
+func (h *callbackHandler) Info() ProtocolInfo {
+	return h.info
+}
+
+func (h *callbackHandler) Authenticate(item *BruteItem) *BruteResult {
+	return h.callback(item)
+}
+
+// NewProtocolHandler 使用元数据和回调函数创建协议处理器
+func NewProtocolHandler(info ProtocolInfo, callback BruteCallback) ProtocolHandler {
+	return &callbackHandler{info: info, callback: callback}
+}
+
+// protocolRegistry 协议注册表
+type protocolRegistry struct {
+	mutex    sync.RWMutex
+	handlers map[string]ProtocolHandler // 协议名 -> 处理器
+	aliases  map[string]string          // 别名 -> 协议名
+}
+
+// registry 全局协议注册表
+var registry = &protocolRegistry{
+	handlers: make(map[string]ProtocolHandler),
+	aliases:  make(map[string]string),
+}
+
+// RegisterProtocol 注册协议处理器，同名的协议会被替换，协议名和别名不区分大小写
+func RegisterProtocol(handler ProtocolHandler) error {
+	info := handler.Info()
+	name := strings.ToLower(info.Name)
+	if name == "" {
+		return fmt.Errorf("protocol name cannot be empty")
+	}
+	switch info.Transport {
+	case "", TransportTCP, TransportUDP:
+	default:
+		return fmt.Errorf("unknown transport for protocol %s: %s", name, info.Transport)
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.handlers[name] = handler
+	for _, alias := range info.Aliases {
+		registry.aliases[strings.ToLower(alias)] = name
+	}
+
+	// 目标解析在 utils 中进行，同步注册默认端口
+	utils.RegisterDefaultPorts(name, info.DefaultPorts)
+	for _, alias := range info.Aliases {
+		utils.RegisterDefaultPorts(alias, info.DefaultPorts)
+	}
+	return nil
+}
+
+// RegisterProtocolHandler 注册需要用户名和密码的协议处理器，兼容只提供回调函数的旧接口
+func RegisterProtocolHandler(protocol string, handler BruteCallback) {
+	if err := RegisterProtocol(NewProtocolHandler(ProtocolInfo{Name: protocol}, handler)); err != nil {
+		gologger.Error().Msgf("Failed to register protocol handler: %v", err)
+	}
+}
+
+// GetProtocol 按协议名或别名获取协议处理器
+func GetProtocol(protocol string) (ProtocolHandler, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	name := strings.ToLower(protocol)
+	if canonical, ok := registry.aliases[name]; ok {
+		name = canonical
+	}
+	handler, exists := registry.handlers[name]
+	return handler, exists
+}
+
+// ResolveProtocol 将别名解析为协议名，未注册的协议返回 false
+func ResolveProtocol(protocol string) (string, bool) {
+	handler, exists := GetProtocol(protocol)
+	if !exists {
+		return "", false
+	}
+	return strings.ToLower(handler.Info().Name), true
+}
+
+// GetProtocolHandler 获取协议的认证函数
+func GetProtocolHandler(protocol string) (BruteCallback, bool) {
+	handler, exists := GetProtocol(protocol)
+	if !exists {
+		return nil, false
+	}
+	return handler.Authenticate, true
+}
+
+// GetSupportedProtocols 获取支持的协议列表，按名称排序，不包括别名
+func GetSupportedProtocols() []string {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	names := make([]string, 0, len(registry.handlers))
+	for name := range registry.handlers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// GetProtocols 获取所有协议的元数据，按名称排序，未声明的传输层填充为 TCP
+func GetProtocols() []ProtocolInfo {
+	infos := make([]ProtocolInfo, 0)
+	for _, name := range GetSupportedProtocols() {
+		if handler, exists := GetProtocol(name); exists {
+			info := handler.Info()
+			if info.Transport == "" {
+				info.Transport = TransportTCP
+			}
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// GetDefaultPorts 获取协议的默认端口
+func GetDefaultPorts(protocol string) []int {
+	handler, exists := GetProtocol(protocol)
+	if !exists {
+		return nil
+	}
+	return slices.Clone(handler.Info().DefaultPorts)
+}
+
+// protocolTransport 返回协议的传输层，未注册的协议视为 TCP
+func protocolTransport(protocol string) Transport {
+	if handler, exists := GetProtocol(protocol); exists && handler.Info().Transport == TransportUDP {
+		return TransportUDP
+	}
+	return TransportTCP
+}
@@ -0,0 +1,236 @@
+package brute
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimit 单层限流设置
+type RateLimit struct {
+	Rate  float64 `json:"rate"`  // 每秒请求数，0 表示不限制
+	Burst int     `json:"burst"` // 突发容量，0 表示与速率相同
+}
+
+// ParseRateLimit 解析 "速率[/突发容量]" 形式的限流设置，例如 "10" 或 "10/20"
+func ParseRateLimit(value string) (RateLimit, error) {
+	var limit RateLimit
+	rateValue, burstValue, hasBurst := strings.Cut(strings.TrimSpace(value), "/")
+
+	r, err := strconv.ParseFloat(rateValue, 64)
+	if err != nil {
+		return limit, fmt.Errorf("invalid rate %q: %w", rateValue, err)
+	}
+	limit.Rate = r
+
+	if hasBurst {
+		burst, err := strconv.Atoi(burstValue)
+		if err != nil {
+			return limit, fmt.Errorf("invalid burst %q: %w", burstValue, err)
+		}
+		limit.Burst = burst
+	}
+	return limit, limit.validate()
+}
+
+// Enabled 是否启用该层限流
+func (l RateLimit) Enabled() bool {
+	return l.Rate > 0
+}
+
+// validate 检查限流设置
+func (l RateLimit) validate() error {
+	if l.Rate < 0 {
+		return fmt.Errorf("rate cannot be negative, got: %v", l.Rate)
+	}
+	if l.Burst < 0 {
+		return fmt.Errorf("burst cannot be negative, got: %d", l.Burst)
+	}
+	return nil
+}
+
+// newLimiter 创建限流器，未启用时不限制
+func (l RateLimit) newLimiter() *rate.Limiter {
+	if !l.Enabled() {
+		return rate.NewLimiter(rate.Inf, 1)
+	}
+	burst := l.Burst
+	if burst == 0 {
+		burst = max(int(l.Rate), 1)
+	}
+	return rate.NewLimiter(rate.Limit(l.Rate), burst)
+}
+
+// RateLayer 限流层
+type RateLayer string
+
+const (
+	RateLayerGlobal   RateLayer = "global"   // 所有请求共享
+	RateLayerHost     RateLayer = "host"     // 每个主机，同一主机上的所有服务共享
+	RateLayerSubnet   RateLayer = "subnet"   // 每个子网，IPv4 为 /24，IPv6 为 /64
+	RateLayerProtocol RateLayer = "protocol" // 每个协议
+)
+
+// RateLayers 返回所有限流层
+func RateLayers() []RateLayer {
+	return []RateLayer{RateLayerGlobal, RateLayerHost, RateLayerSubnet, RateLayerProtocol}
+}
+
+// rateLimiters 分层限流器：全局、每个主机、每个子网、每个协议
+// 同一层中相同键的目标共享一个限流器，例如同一主机上的不同服务共享主机级别的速率
+type rateLimiters struct {
+	global    *rate.Limiter
+	fromDelay atomic.Bool // 全局限流器由 MinDelay 换算而来，运行时调整延迟时一并更新
+
+	mutex     sync.RWMutex            // 保护各层的当前设置，调整设置时与创建限流器互斥
+	limits    map[RateLayer]RateLimit // 主机、子网和协议层的当前设置
+	hosts     sync.Map                // 主机 -> *rate.Limiter
+	subnets   sync.Map                // 子网 -> *rate.Limiter
+	protocols sync.Map                // 协议 -> *rate.Limiter
+}
+
+// newRateLimiters 根据配置创建分层限流器
+// 未设置全局限流时与之前的版本相同，MinDelay 同时作为全局速率，每隔 MinDelay 允许一个请求，突发容量为 TargetConcurrent
+func newRateLimiters(config *Config) *rateLimiters {
+	limiters := &rateLimiters{
+		limits: map[RateLayer]RateLimit{
+			RateLayerHost:     config.HostRateLimit,
+			RateLayerSubnet:   config.SubnetRateLimit,
+			RateLayerProtocol: config.ProtocolRateLimit,
+		},
+	}
+	if !config.GlobalRateLimit.Enabled() && config.MinDelay > 0 {
+		limiters.global = rate.NewLimiter(rate.Every(config.MinDelay), max(config.TargetConcurrent, 1))
+		limiters.fromDelay.Store(true)
+	} else {
+		limiters.global = config.GlobalRateLimit.newLimiter()
+	}
+	return limiters
+}
+
+// forTarget 返回目标需要经过的所有限流器
+// 未启用的层同样包含在内，使用不限速的限流器，以便运行时通过 SetRateLimit 启用
+func (l *rateLimiters) forTarget(serviceType, host string) []*rate.Limiter {
+	limiters := []*rate.Limiter{l.global, l.load(RateLayerHost, host)}
+	if subnet, ok := subnetOf(host); ok {
+		limiters = append(limiters, l.load(RateLayerSubnet, subnet))
+	}
+	return append(limiters, l.load(RateLayerProtocol, serviceType))
+}
+
+// layer 返回限流层中各个键的限流器
+func (l *rateLimiters) layer(layer RateLayer) *sync.Map {
+	switch layer {
+	case RateLayerHost:
+		return &l.hosts
+	case RateLayerSubnet:
+		return &l.subnets
+	case RateLayerProtocol:
+		return &l.protocols
+	}
+	return nil
+}
+
+// load 获取或按该层的当前设置创建指定键的限流器
+func (l *rateLimiters) load(layer RateLayer, key string) *rate.Limiter {
+	limiters := l.layer(layer)
+	if limiter, ok := limiters.Load(key); ok {
+		return limiter.(*rate.Limiter)
+	}
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	limiter, _ := limiters.LoadOrStore(key, l.limits[layer].newLimiter())
+	return limiter.(*rate.Limiter)
+}
+
+// set 调整一层的限流设置，该层已创建的限流器一并更新
+func (l *rateLimiters) set(layer RateLayer, limit RateLimit) error {
+	updated := limit.newLimiter()
+	update := func(limiter *rate.Limiter) {
+		limiter.SetLimit(updated.Limit())
+		limiter.SetBurst(updated.Burst())
+	}
+
+	if layer == RateLayerGlobal {
+		l.fromDelay.Store(false)
+		update(l.global)
+		return nil
+	}
+	limiters := l.layer(layer)
+	if limiters == nil {
+		return fmt.Errorf("unknown rate limit layer %q", layer)
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.limits[layer] = limit
+	limiters.Range(func(_, limiter interface{}) bool {
+		update(limiter.(*rate.Limiter))
+		return true
+	})
+	return nil
+}
+
+// subnetOf 返回地址所在的子网，IPv4 为 /24，IPv6 为 /64，域名没有子网
+func subnetOf(host string) (string, bool) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "", false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return (&net.IPNet{IP: ip4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String(), true
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}).String(), true
+}
+
+// throttle 在每次尝试前等待目标的请求间隔（包括自适应控制附加的延迟）和各层限流器，目标被放弃或引擎停止时返回 false
+func (e *Engine) throttle(process *targetProcess) bool {
+	// 暂停期间已分配槽位的任务也不会发起新的尝试
+	if !e.waitResumed(process.ctx) {
+		return false
+	}
+
+	if wait := process.reserveDelay(e.randomDelay() + process.adaptive.extraDelay()); wait > 0 {
+		if !sleepContext(process.ctx, wait) {
+			return false
+		}
+	}
+
+	for _, limiter := range process.limiters {
+		if err := limiter.Wait(process.ctx); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// randomDelay 返回 MinDelay 到 MaxDelay 之间的随机延迟，未设置 MaxDelay 时使用固定的 MinDelay
+func (e *Engine) randomDelay() time.Duration {
+	minDelay := time.Duration(atomic.LoadInt64(&e.minDelay))
+	maxDelay := time.Duration(atomic.LoadInt64(&e.maxDelay))
+	if maxDelay <= minDelay {
+		return minDelay
+	}
+	return minDelay + rand.N(maxDelay-minDelay+1)
+}
+
+// reserveDelay 预留目标的下一次请求时间，返回需要等待的时长
+// 同一目标相邻两次请求的开始时间至少间隔 delay，并发的工作协程依次排队
+func (p *targetProcess) reserveDelay(delay time.Duration) time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	at := now
+	if p.nextAttempt.After(now) {
+		at = p.nextAttempt
+	}
+	p.nextAttempt = at.Add(delay)
+	return at.Sub(now)
+}
@@ -0,0 +1,41 @@
+package brute
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// retryBackoff 计算第 attempt 次失败后的退避时间，指数增长并加入随机抖动
+func (e *Engine) retryBackoff(attempt int) time.Duration {
+	base := e.config.RetryBackoff
+	if base <= 0 {
+		return 0
+	}
+
+	backoff := base << uint(attempt-1)
+	if max := e.config.MaxRetryBackoff; max > 0 && (backoff > max || backoff <= 0) {
+		backoff = max
+	}
+
+	// 在 [backoff/2, backoff] 之间随机，避免多个任务同时重试
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
+
+// sleepContext 可被上下文取消的休眠，返回 false 表示上下文已取消
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
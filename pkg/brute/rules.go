@@ -0,0 +1,277 @@
+package brute
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Rule hashcat 风格的密码变形规则，由一个或多个操作组成，例如 c$2$0$2$4 将 admin 变为 Admin2024
+// 支持的操作：
+//
+//	:     保持不变              l     全部小写            u     全部大写
+//	c     首字母大写其余小写    C     首字母小写其余大写  t     切换所有字母大小写
+//	TN    切换第 N 个字符大小写 r     反转                d     重复一次
+//	pN    追加 N 次自身         f     追加反转后的自身    $X    末尾追加字符 X
+//	^X    开头插入字符 X        [     删除首字符          ]     删除末字符
+//	DN    删除第 N 个字符       'N    截断为前 N 个字符   sXY   将所有 X 替换为 Y
+//	@X    删除所有字符 X
+//
+// 位置 N 使用 0-9 和 A-Z 表示 0 到 35，操作之间的空格被忽略
+type Rule struct {
+	text string
+	ops  []ruleOp
+}
+
+// ruleOp 规则中的单个操作
+type ruleOp struct {
+	name rune
+	args []rune
+}
+
+// ruleArgs 每个操作的参数个数，参数为字符或位置，字符参数可以是任意 Unicode 字符
+var ruleArgs = map[rune]int{
+	':': 0, 'l': 0, 'u': 0, 'c': 0, 'C': 0, 't': 0, 'r': 0, 'd': 0, 'f': 0, '[': 0, ']': 0,
+	'T': 1, 'p': 1, 'D': 1, '\'': 1, '$': 1, '^': 1, '@': 1,
+	's': 2,
+}
+
+// rulePositionOps 参数为位置的操作
+var rulePositionOps = []rune{'T', 'p', 'D', '\''}
+
+// ParseRule 解析一条规则
+func ParseRule(text string) (Rule, error) {
+	rule := Rule{text: text}
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		name := runes[i]
+		i++
+		if name == ' ' {
+			continue
+		}
+		count, ok := ruleArgs[name]
+		if !ok {
+			return Rule{}, fmt.Errorf("unsupported rule operation %q in %q", name, text)
+		}
+		if i+count > len(runes) {
+			return Rule{}, fmt.Errorf("rule operation %q in %q needs %d argument(s)", name, text, count)
+		}
+		op := ruleOp{name: name, args: runes[i : i+count]}
+		i += count
+		if slices.Contains(rulePositionOps, name) {
+			if _, ok := rulePosition(op.args[0]); !ok {
+				return Rule{}, fmt.Errorf("invalid position %q for rule operation %q in %q", op.args[0], name, text)
+			}
+		}
+		rule.ops = append(rule.ops, op)
+	}
+	return rule, nil
+}
+
+// LoadRules 从文件载入规则，空行和以 # 开头的注释行会被忽略
+func LoadRules(path string) ([]Rule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []Rule
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule, err := ParseRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// String 返回规则的原始文本
+func (r Rule) String() string {
+	return r.text
+}
+
+// Apply 将规则应用到一个词
+func (r Rule) Apply(word string) string {
+	runes := []rune(word)
+	for _, op := range r.ops {
+		switch op.name {
+		case 'l':
+			runes = []rune(strings.ToLower(string(runes)))
+		case 'u':
+			runes = []rune(strings.ToUpper(string(runes)))
+		case 'c', 'C':
+			lower, upper := unicode.ToLower, unicode.ToUpper
+			if op.name == 'C' {
+				lower, upper = upper, lower
+			}
+			for i := range runes {
+				if i == 0 {
+					runes[i] = upper(runes[i])
+				} else {
+					runes[i] = lower(runes[i])
+				}
+			}
+		case 't':
+			for i := range runes {
+				runes[i] = toggleCase(runes[i])
+			}
+		case 'T':
+			if n, _ := rulePosition(op.args[0]); n < len(runes) {
+				runes[n] = toggleCase(runes[n])
+			}
+		case 'r':
+			slices.Reverse(runes)
+		case 'd':
+			runes = append(runes, runes...)
+		case 'p':
+			n, _ := rulePosition(op.args[0])
+			runes = slices.Repeat(runes, n+1)
+		case 'f':
+			runes = append(runes, reversed(runes)...)
+		case '$':
+			runes = append(runes, op.args[0])
+		case '^':
+			runes = append([]rune{op.args[0]}, runes...)
+		case '[':
+			if len(runes) > 0 {
+				runes = runes[1:]
+			}
+		case ']':
+			if len(runes) > 0 {
+				runes = runes[:len(runes)-1]
+			}
+		case 'D':
+			if n, _ := rulePosition(op.args[0]); n < len(runes) {
+				runes = slices.Delete(runes, n, n+1)
+			}
+		case '\'':
+			if n, _ := rulePosition(op.args[0]); n < len(runes) {
+				runes = runes[:n]
+			}
+		case 's':
+			for i := range runes {
+				if runes[i] == op.args[0] {
+					runes[i] = op.args[1]
+				}
+			}
+		case '@':
+			runes = slices.DeleteFunc(runes, func(c rune) bool {
+				return c == op.args[0]
+			})
+		}
+	}
+	return string(runes)
+}
+
+// rulePosition 解析位置参数，0-9 和 A-Z 表示 0 到 35
+func rulePosition(c rune) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10, true
+	}
+	return 0, false
+}
+
+// toggleCase 切换字母大小写
+func toggleCase(c rune) rune {
+	if unicode.IsUpper(c) {
+		return unicode.ToLower(c)
+	}
+	return unicode.ToUpper(c)
+}
+
+// reversed 返回反转后的副本
+func reversed(runes []rune) []rune {
+	copied := slices.Clone(runes)
+	slices.Reverse(copied)
+	return copied
+}
+
+// RuleWordlist 对字典中的每个词依次应用所有规则，变形结果在遍历时逐个生成，不会载入内存
+// 与 hashcat 相同，只输出规则的结果，需要保留原词时在规则中加入 :
+// 同一个词产生的重复结果只输出一次，非空的词变形为空时丢弃
+type RuleWordlist struct {
+	Base  Wordlist
+	Rules []Rule
+
+	countOnce sync.Once
+	count     int64
+	countErr  error
+}
+
+// NewRuleWordlist 创建规则变形字典
+func NewRuleWordlist(base Wordlist, rules []Rule) *RuleWordlist {
+	return &RuleWordlist{Base: base, Rules: rules}
+}
+
+// Open 打开遍历游标
+func (w *RuleWordlist) Open() (WordCursor, error) {
+	cursor, err := w.Base.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &ruleCursor{base: cursor, rules: w.Rules}, nil
+}
+
+// Count 返回变形后的条目数，首次调用时遍历一遍字典并缓存结果，只占用单个词的变形结果的内存
+func (w *RuleWordlist) Count() (int64, error) {
+	w.countOnce.Do(func() {
+		cursor, err := w.Open()
+		if err != nil {
+			w.countErr = err
+			return
+		}
+		defer cursor.Close()
+		for {
+			if _, ok := cursor.Next(); !ok {
+				break
+			}
+			w.count++
+		}
+	})
+	return w.count, w.countErr
+}
+
+// ruleCursor 规则变形字典游标，每次读取一个词并生成它的全部变形
+type ruleCursor struct {
+	base    WordCursor
+	rules   []Rule
+	pending []string
+}
+
+func (c *ruleCursor) Next() (string, bool) {
+	for len(c.pending) == 0 {
+		word, ok := c.base.Next()
+		if !ok {
+			return "", false
+		}
+		seen := make(map[string]bool, len(c.rules))
+		for _, rule := range c.rules {
+			mutated := rule.Apply(word)
+			if (mutated != "" || word == "") && !seen[mutated] {
+				seen[mutated] = true
+				c.pending = append(c.pending, mutated)
+			}
+		}
+	}
+	word := c.pending[0]
+	c.pending = c.pending[1:]
+	return word, true
+}
+
+func (c *ruleCursor) Close() error {
+	return c.base.Close()
+}
@@ -0,0 +1,206 @@
+package brute
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// TargetOrder 目标进入工作槽位的顺序
+type TargetOrder string
+
+const (
+	TargetOrderInput   TargetOrder = "input"   // 按添加顺序
+	TargetOrderShuffle TargetOrder = "shuffle" // 随机顺序，指定 ShuffleSeed 时可以复现
+	TargetOrderSubnet  TargetOrder = "subnet"  // 各子网轮流调度，分散对同一网段的压力
+)
+
+// TargetOrders 返回所有支持的目标调度顺序
+func TargetOrders() []TargetOrder {
+	return []TargetOrder{TargetOrderInput, TargetOrderShuffle, TargetOrderSubnet}
+}
+
+// targetQueue 等待工作槽位的目标队列，排队的目标不占用协程
+type targetQueue struct {
+	order   TargetOrder
+	random  *rand.Rand
+	pending []*targetProcess            // input 和 shuffle 顺序下的队列
+	subnets map[string][]*targetProcess // subnet 顺序下各子网的队列
+	ring    []string                    // 有排队目标的子网，按首次入队的顺序轮流调度
+	cursor  int                         // 下一个调度的子网
+	size    int
+}
+
+// newTargetQueue 创建目标队列，未指定顺序时按添加顺序，seed 为 0 时随机顺序每次不同
+func newTargetQueue(order TargetOrder, seed int64) *targetQueue {
+	if order == "" {
+		order = TargetOrderInput
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &targetQueue{
+		order:   order,
+		random:  rand.New(rand.NewPCG(uint64(seed), uint64(seed))),
+		subnets: make(map[string][]*targetProcess),
+	}
+}
+
+// push 将目标加入队列
+func (q *targetQueue) push(process *targetProcess) {
+	q.size++
+	if q.order != TargetOrderSubnet {
+		q.pending = append(q.pending, process)
+		return
+	}
+
+	// 非 IP 地址的目标以主机名作为分组
+	subnet, ok := subnetOf(process.host)
+	if !ok {
+		subnet = process.host
+	}
+	if len(q.subnets[subnet]) == 0 {
+		q.ring = append(q.ring, subnet)
+	}
+	q.subnets[subnet] = append(q.subnets[subnet], process)
+}
+
+// pop 按调度顺序取出下一个目标
+func (q *targetQueue) pop() (*targetProcess, bool) {
+	if q.size == 0 {
+		return nil, false
+	}
+	q.size--
+
+	switch q.order {
+	case TargetOrderSubnet:
+		subnet := q.ring[q.cursor]
+		queue := q.subnets[subnet]
+		process := queue[0]
+		queue[0] = nil
+		if len(queue) == 1 {
+			// 子网已无排队目标，移出轮转
+			delete(q.subnets, subnet)
+			q.ring = append(q.ring[:q.cursor], q.ring[q.cursor+1:]...)
+		} else {
+			q.subnets[subnet] = queue[1:]
+			q.cursor++
+		}
+		if q.cursor >= len(q.ring) {
+			q.cursor = 0
+		}
+		return process, true
+	case TargetOrderShuffle:
+		i := q.random.IntN(len(q.pending))
+		last := len(q.pending) - 1
+		process := q.pending[i]
+		q.pending[i] = q.pending[last]
+		q.pending[last] = nil
+		q.pending = q.pending[:last]
+		return process, true
+	default:
+		process := q.pending[0]
+		q.pending[0] = nil
+		q.pending = q.pending[1:]
+		return process, true
+	}
+}
+
+// len 返回排队的目标数
+func (q *targetQueue) len() int {
+	return q.size
+}
+
+// enqueueTarget 将目标加入调度队列，有空闲的工作槽位时立即开始处理
+func (e *Engine) enqueueTarget(process *targetProcess) {
+	e.targetWg.Add(1)
+	// 排队时即加入喷洒域，排队的目标同样需要完成当前窗口的一轮
+	if e.config.Strategy == StrategySpray {
+		e.joinRealm(process)
+	}
+
+	e.queueMutex.Lock()
+	defer e.queueMutex.Unlock()
+	e.queue.push(process)
+	e.dispatch()
+}
+
+// dispatch 按调度顺序为排队的目标分配空闲的工作槽位，调用方需持有 queueMutex
+func (e *Engine) dispatch() {
+	for e.dispatching && e.activeTargets < e.activeLimit {
+		process, ok := e.queue.pop()
+		if !ok {
+			return
+		}
+		// 引擎停止后排队的目标不再处理
+		if e.ctx.Err() != nil {
+			e.targetWg.Done()
+			continue
+		}
+
+		e.activeTargets++
+		go e.processTarget(process)
+	}
+}
+
+// startDispatching 开始从队列中调度目标
+func (e *Engine) startDispatching() {
+	e.queueMutex.Lock()
+	defer e.queueMutex.Unlock()
+	e.dispatching = true
+	e.dispatch()
+}
+
+// releaseTargetSlot 目标处理结束或挂起后释放工作槽位
+func (e *Engine) releaseTargetSlot() {
+	e.queueMutex.Lock()
+	defer e.queueMutex.Unlock()
+	e.activeTargets--
+	e.dispatch()
+}
+
+// setActiveLimit 调整同时处理的目标数上限，调小时正在处理的目标不受影响
+func (e *Engine) setActiveLimit(limit int) {
+	e.queueMutex.Lock()
+	defer e.queueMutex.Unlock()
+	e.activeLimit = limit
+	e.dispatch()
+}
+
+// park 目标暂时没有任务时挂起并让出工作槽位，Feed 或 FeedSource 提交新的任务后重新排队
+// 返回 parked 表示已挂起，pending 表示已有新的任务，两者都为 false 时目标不会再有任务
+func (e *Engine) park(process *targetProcess) (parked, pending bool) {
+	// 与 Close 互斥，关闭后挂起的目标由 Close 重新排队
+	e.targetsMutex.RLock()
+	defer e.targetsMutex.RUnlock()
+
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+	if process.Finished {
+		return false, false
+	}
+	if len(process.Items) > 0 || len(process.sources) > 0 {
+		return false, true
+	}
+	if e.isClosed() || process.ctx.Err() != nil {
+		return false, false
+	}
+
+	// 挂起期间不阻塞同一喷洒域内的其他目标
+	e.leaveRealm(process)
+	process.parked = true
+	return true, false
+}
+
+// unpark 取消目标的挂起状态，返回目标是否需要重新排队，调用方需持有 process.mutex
+func (p *targetProcess) unpark() bool {
+	parked := p.parked
+	p.parked = false
+	return parked
+}
+
+// GetTargetQueueStatus 获取正在处理和排队等待的目标数，挂起的目标不计入
+func (e *Engine) GetTargetQueueStatus() (active, queued int) {
+	e.queueMutex.Lock()
+	defer e.queueMutex.Unlock()
+	return e.activeTargets, e.queue.len()
+}
@@ -0,0 +1,75 @@
+package brute
+
+import (
+	"context"
+	"sync"
+)
+
+// semaphore 容量可在运行时调整的信号量
+// 缩小容量时不会中断已持有的槽位，只是在占用数降到新容量以下之前不再发放新的槽位
+type semaphore struct {
+	mutex   sync.Mutex
+	limit   int
+	used    int
+	changed chan struct{} // 状态变化时关闭并替换，用于唤醒等待者
+}
+
+// newSemaphore 创建信号量
+func newSemaphore(limit int) *semaphore {
+	return &semaphore{
+		limit:   limit,
+		changed: make(chan struct{}),
+	}
+}
+
+// acquire 获取一个槽位，上下文取消时返回 false
+func (s *semaphore) acquire(ctx context.Context) bool {
+	for {
+		s.mutex.Lock()
+		if s.used < s.limit {
+			s.used++
+			s.mutex.Unlock()
+			return true
+		}
+		changed := s.changed
+		s.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// release 释放一个槽位
+func (s *semaphore) release() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.used--
+	s.notify()
+}
+
+// resize 调整信号量容量
+func (s *semaphore) resize(limit int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.limit == limit {
+		return
+	}
+	s.limit = limit
+	s.notify()
+}
+
+// status 返回已占用的槽位数和当前容量
+func (s *semaphore) status() (used, limit int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.used, s.limit
+}
+
+// notify 唤醒所有等待者，调用方需持有锁
+func (s *semaphore) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
@@ -0,0 +1,208 @@
+package brute
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// SessionReuseAll 启用所有支持会话复用的协议
+const SessionReuseAll = "all"
+
+// Session 可以连续尝试多组凭据的认证会话，例如一个 SSH 或 FTP 连接
+type Session interface {
+	// Accepts 返回会话能否尝试该任务项，例如 SSH 会话只能尝试建立连接时的用户名
+	Accepts(item *BruteItem) bool
+	// Authenticate 在会话上尝试一组凭据，reusable 为 false 时会话不能再使用，例如认证成功或服务端断开
+	Authenticate(item *BruteItem) (result *BruteResult, reusable bool)
+	// Close 关闭会话
+	Close() error
+}
+
+// SessionFactory 为任务项建立新的会话，会话的连接绑定到 ctx，ctx 结束时关闭
+type SessionFactory func(ctx context.Context, item *BruteItem) (Session, error)
+
+// SessionProtocol 支持会话复用的协议处理器
+type SessionProtocol interface {
+	ProtocolHandler
+	// NewSession 建立到任务项目标的会话
+	NewSession(ctx context.Context, item *BruteItem) (Session, error)
+}
+
+// sessionHandler 为回调函数形式的协议处理器附加会话工厂
+type sessionHandler struct {
+	callbackHandler
+	factory SessionFactory
+}
+
+func (h *sessionHandler) NewSession(ctx context.Context, item *BruteItem) (Session, error) {
+	return h.factory(ctx, item)
+}
+
+// NewSessionProtocolHandler 创建支持会话复用的协议处理器，未启用复用时使用 callback 逐次认证
+func NewSessionProtocolHandler(info ProtocolInfo, callback BruteCallback, factory SessionFactory) ProtocolHandler {
+	return &sessionHandler{
+		callbackHandler: callbackHandler{info: info, callback: callback},
+		factory:         factory,
+	}
+}
+
+// SupportsSessionReuse 返回协议是否支持会话复用
+func SupportsSessionReuse(protocol string) bool {
+	handler, exists := GetProtocol(protocol)
+	if !exists {
+		return false
+	}
+	_, ok := handler.(SessionProtocol)
+	return ok
+}
+
+// sessionReuse 返回协议是否启用了会话复用
+func (c *Config) sessionReuse(protocol string) bool {
+	if len(c.SessionReuse) == 0 || !SupportsSessionReuse(protocol) {
+		return false
+	}
+	if slices.Contains(c.SessionReuse, SessionReuseAll) {
+		return true
+	}
+	name, _ := ResolveProtocol(protocol)
+	return slices.ContainsFunc(c.SessionReuse, func(p string) bool {
+		resolved, ok := ResolveProtocol(p)
+		return ok && resolved == name
+	})
+}
+
+// validateSessionReuse 检查启用会话复用的协议是否都支持复用
+func validateSessionReuse(protocols []string) error {
+	for _, protocol := range protocols {
+		if protocol == SessionReuseAll {
+			continue
+		}
+		if _, exists := GetProtocol(protocol); !exists {
+			return fmt.Errorf("unknown protocol for session reuse: %s", protocol)
+		}
+		if !SupportsSessionReuse(protocol) {
+			return fmt.Errorf("protocol %s does not support session reuse", protocol)
+		}
+	}
+	return nil
+}
+
+// pooledSession 空闲池中的会话及其已尝试的次数
+type pooledSession struct {
+	Session
+	attempts int
+}
+
+// sessionPool 目标的空闲会话，同一时间每个会话只被一个任务使用
+type sessionPool struct {
+	mutex  sync.Mutex
+	idle   []*pooledSession
+	closed bool // 目标处理结束后不再接收会话
+}
+
+// get 取出最近归还的可以尝试该任务项的会话，没有时返回 nil
+// 不能尝试该任务项的空闲会话一并关闭，例如字典已经转到下一个用户名后之前用户名的 SSH 会话
+func (p *sessionPool) get(item *BruteItem) *pooledSession {
+	p.mutex.Lock()
+	var found *pooledSession
+	var stale []*pooledSession
+	for i := len(p.idle) - 1; i >= 0; i-- {
+		session := p.idle[i]
+		switch {
+		case !session.Accepts(item):
+			stale = append(stale, session)
+		case found == nil:
+			found = session
+		default:
+			continue
+		}
+		p.idle = slices.Delete(p.idle, i, i+1)
+	}
+	p.mutex.Unlock()
+
+	for _, session := range stale {
+		session.Close()
+	}
+	return found
+}
+
+// put 归还会话，空闲会话最多保留 limit 个，超出时关闭最早归还的会话，目标已结束时关闭会话
+func (p *sessionPool) put(session *pooledSession, limit int) {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		session.Close()
+		return
+	}
+	p.idle = append(p.idle, session)
+	var evicted []*pooledSession
+	if excess := len(p.idle) - max(limit, 1); excess > 0 {
+		evicted = slices.Clone(p.idle[:excess])
+		p.idle = slices.Delete(p.idle, 0, excess)
+	}
+	p.mutex.Unlock()
+
+	for _, session := range evicted {
+		session.Close()
+	}
+}
+
+// drain 关闭所有空闲会话，close 为 true 时之后归还的会话也直接关闭
+func (p *sessionPool) drain(close bool) {
+	p.mutex.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = p.closed || close
+	p.mutex.Unlock()
+
+	for _, session := range idle {
+		session.Close()
+	}
+}
+
+// executeWithSession 在目标的会话上尝试任务项，没有可用的空闲会话时建立新的会话
+func (e *Engine) executeWithSession(item *BruteItem, process *targetProcess, protocol SessionProtocol) *BruteResult {
+	for {
+		session := process.sessions.get(item)
+		reused := session != nil
+		if !reused {
+			created, err := protocol.NewSession(process.ctx, item)
+			if err != nil {
+				return NewResult(item).FailWithError(err)
+			}
+			atomic.AddInt64(&e.sessionsOpened, 1)
+			session = &pooledSession{Session: created}
+		}
+
+		result, reusable := session.Authenticate(item)
+		session.attempts++
+
+		// 空闲的会话可能已被服务端关闭，换用新的会话尝试，不计为重试
+		if reused && !result.Success && result.FailureKind.IsNetwork() {
+			gologger.Debug().Msgf("Idle session to %s closed (%s), opening a new one", process.Target, result.FailureKind)
+			session.Close()
+			continue
+		}
+		if reused {
+			atomic.AddInt64(&e.sessionReuses, 1)
+		}
+
+		maxAttempts := e.config.MaxSessionAttempts
+		if reusable && (maxAttempts == 0 || session.attempts < maxAttempts) && process.ctx.Err() == nil {
+			process.sessions.put(session, e.getTaskConcurrent())
+		} else {
+			session.Close()
+		}
+		return result
+	}
+}
+
+// GetSessionStats 获取建立的会话数和复用已有会话的尝试次数
+func (e *Engine) GetSessionStats() (opened, reused int64) {
+	return atomic.LoadInt64(&e.sessionsOpened), atomic.LoadInt64(&e.sessionReuses)
+}
@@ -0,0 +1,449 @@
+package brute
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// ItemSource 爆破任务源，按需惰性产生任务项
+// 引擎只在有空闲工作槽时才调用 Next，因此大规模扫描无需预先生成全部任务
+type ItemSource interface {
+	// Next 返回下一个任务项，没有更多任务时返回 false
+	Next() (*BruteItem, bool)
+	// Total 返回任务源的任务总数，用于进度统计
+	Total() int64
+	// Close 释放任务源持有的资源（如打开的字典文件）
+	Close() error
+}
+
+// Wordlist 可重复遍历的字典
+type Wordlist interface {
+	// Open 打开一个新的遍历游标
+	Open() (WordCursor, error)
+	// Count 返回字典条目数
+	Count() (int64, error)
+}
+
+// WordCursor 字典遍历游标
+type WordCursor interface {
+	// Next 返回下一个条目，遍历结束时返回 false
+	Next() (string, bool)
+	// Close 关闭游标
+	Close() error
+}
+
+// SliceWordlist 内存字典
+type SliceWordlist []string
+
+// Open 打开遍历游标
+func (w SliceWordlist) Open() (WordCursor, error) {
+	return &sliceCursor{words: w}, nil
+}
+
+// Count 返回字典条目数
+func (w SliceWordlist) Count() (int64, error) {
+	return int64(len(w)), nil
+}
+
+// sliceCursor 内存字典游标
+type sliceCursor struct {
+	words []string
+	index int
+}
+
+func (c *sliceCursor) Next() (string, bool) {
+	if c.index >= len(c.words) {
+		return "", false
+	}
+	word := c.words[c.index]
+	c.index++
+	return word, true
+}
+
+func (c *sliceCursor) Close() error {
+	return nil
+}
+
+// FileWordlist 文件字典，每次遍历时按行流式读取，不会将整个文件载入内存
+// 空行和以 # 开头的注释行会被忽略
+type FileWordlist struct {
+	Path string
+
+	countOnce sync.Once
+	count     int64
+	countErr  error
+}
+
+// NewFileWordlist 创建文件字典
+func NewFileWordlist(path string) *FileWordlist {
+	return &FileWordlist{Path: path}
+}
+
+// Open 打开遍历游标
+func (w *FileWordlist) Open() (WordCursor, error) {
+	file, err := os.Open(w.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &fileCursor{file: file, scanner: bufio.NewScanner(file)}, nil
+}
+
+// Count 返回字典条目数，首次调用时扫描一遍文件并缓存结果
+func (w *FileWordlist) Count() (int64, error) {
+	w.countOnce.Do(func() {
+		cursor, err := w.Open()
+		if err != nil {
+			w.countErr = err
+			return
+		}
+		defer cursor.Close()
+		for {
+			if _, ok := cursor.Next(); !ok {
+				break
+			}
+			w.count++
+		}
+		w.countErr = cursor.(*fileCursor).scanner.Err()
+	})
+	return w.count, w.countErr
+}
+
+// fileCursor 文件字典游标
+type fileCursor struct {
+	file    *os.File
+	scanner *bufio.Scanner
+}
+
+func (c *fileCursor) Next() (string, bool) {
+	for c.scanner.Scan() {
+		line := strings.TrimSpace(c.scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line, true
+		}
+	}
+	return "", false
+}
+
+func (c *fileCursor) Close() error {
+	return c.file.Close()
+}
+
+// ChainWordlists 将多个字典串联为一个字典，按顺序依次遍历
+func ChainWordlists(lists ...Wordlist) Wordlist {
+	var chained chainWordlist
+	for _, list := range lists {
+		if list != nil {
+			chained = append(chained, list)
+		}
+	}
+	return chained
+}
+
+// ExcludeWordlist 跳过字典中出现在 exclude 里的词，例如字典文件中已经在内存字典里尝试过的词
+// 排除集合在创建时建立一次，所有游标共享，遍历时不记录已输出的词，大字典仍然流式读取
+func ExcludeWordlist(list Wordlist, exclude []string) Wordlist {
+	if len(exclude) == 0 {
+		return list
+	}
+	set := make(map[string]struct{}, len(exclude))
+	for _, word := range exclude {
+		set[word] = struct{}{}
+	}
+	return &excludeWordlist{list: list, exclude: set}
+}
+
+// excludeWordlist 排除指定词的字典
+type excludeWordlist struct {
+	list    Wordlist
+	exclude map[string]struct{}
+
+	countOnce sync.Once
+	count     int64
+	countErr  error
+}
+
+func (w *excludeWordlist) Open() (WordCursor, error) {
+	cursor, err := w.list.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &excludeCursor{cursor: cursor, exclude: w.exclude}, nil
+}
+
+// Count 返回排除后的条目数，首次调用时遍历一遍字典并缓存结果
+func (w *excludeWordlist) Count() (int64, error) {
+	w.countOnce.Do(func() {
+		cursor, err := w.Open()
+		if err != nil {
+			w.countErr = err
+			return
+		}
+		for {
+			if _, ok := cursor.Next(); !ok {
+				break
+			}
+			w.count++
+		}
+		w.countErr = cursor.Close()
+	})
+	return w.count, w.countErr
+}
+
+// excludeCursor 排除指定词的字典游标
+type excludeCursor struct {
+	cursor  WordCursor
+	exclude map[string]struct{}
+}
+
+func (c *excludeCursor) Next() (string, bool) {
+	for {
+		word, ok := c.cursor.Next()
+		if !ok {
+			return "", false
+		}
+		if _, excluded := c.exclude[word]; !excluded {
+			return word, true
+		}
+	}
+}
+
+func (c *excludeCursor) Close() error {
+	return c.cursor.Close()
+}
+
+// chainWordlist 串联字典
+type chainWordlist []Wordlist
+
+func (w chainWordlist) Open() (WordCursor, error) {
+	return &chainCursor{lists: w}, nil
+}
+
+func (w chainWordlist) Count() (int64, error) {
+	var total int64
+	for _, list := range w {
+		count, err := list.Count()
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// chainCursor 串联字典游标
+type chainCursor struct {
+	lists   []Wordlist
+	current WordCursor
+	err     error
+}
+
+func (c *chainCursor) Next() (string, bool) {
+	for {
+		if c.current == nil {
+			if len(c.lists) == 0 {
+				return "", false
+			}
+			cursor, err := c.lists[0].Open()
+			c.lists = c.lists[1:]
+			if err != nil {
+				c.err = err
+				gologger.Error().Msgf("Failed to open wordlist: %v", err)
+				continue
+			}
+			c.current = cursor
+		}
+		if word, ok := c.current.Next(); ok {
+			return word, true
+		}
+		c.current.Close()
+		c.current = nil
+	}
+}
+
+func (c *chainCursor) Close() error {
+	if c.current != nil {
+		return c.current.Close()
+	}
+	return c.err
+}
+
+// sliceSource 基于切片的任务源，用于 Feed 逐个提交的任务项
+type sliceSource struct {
+	items []*BruteItem
+	total int64
+}
+
+// NewSliceSource 创建基于切片的任务源
+func NewSliceSource(items []*BruteItem) ItemSource {
+	return &sliceSource{items: items, total: int64(len(items))}
+}
+
+func (s *sliceSource) Next() (*BruteItem, bool) {
+	if len(s.items) == 0 {
+		return nil, false
+	}
+	item := s.items[0]
+	s.items[0] = nil
+	s.items = s.items[1:]
+	return item, true
+}
+
+func (s *sliceSource) Total() int64 {
+	return s.total
+}
+
+func (s *sliceSource) Close() error {
+	s.items = nil
+	return nil
+}
+
+// credentialSource 按 用户名×密码 的顺序惰性生成任务项
+// 内层字典在外层每个词开始时重新打开，字典文件只在遍历时按行读取
+// 默认外层为用户名，密码喷洒模式下外层为密码，每轮对所有用户名尝试同样的密码
+type credentialSource struct {
+	template  BruteItem
+	users     Wordlist
+	passwords Wordlist
+	total     int64
+	spray     bool // 是否按密码优先的顺序生成
+	perRound  int  // 密码喷洒模式下每轮的密码数
+
+	outerCursor WordCursor
+	innerCursor WordCursor
+	current     string // 当前外层的词
+	index       int    // 当前外层的词的序号
+	done        bool
+}
+
+// newCredentialSource 创建凭据任务源，template 提供除用户名和密码外的任务字段
+func newCredentialSource(template BruteItem, users, passwords Wordlist) (*credentialSource, error) {
+	userCount, err := users.Count()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count usernames: %w", err)
+	}
+	passCount, err := passwords.Count()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count passwords: %w", err)
+	}
+
+	return &credentialSource{
+		template:  template,
+		users:     users,
+		passwords: passwords,
+		total:     userCount * passCount,
+		index:     -1,
+	}, nil
+}
+
+// newSpraySource 创建密码喷洒任务源，按密码优先的顺序生成任务项，每 perRound 个密码为一轮
+func newSpraySource(template BruteItem, users, passwords Wordlist, perRound int) (*credentialSource, error) {
+	source, err := newCredentialSource(template, users, passwords)
+	if err != nil {
+		return nil, err
+	}
+	source.spray = true
+	source.perRound = max(perRound, 1)
+	return source, nil
+}
+
+func (s *credentialSource) Next() (*BruteItem, bool) {
+	for !s.done {
+		if s.innerCursor == nil {
+			if !s.nextOuter() {
+				s.Close()
+				return nil, false
+			}
+		}
+
+		word, ok := s.innerCursor.Next()
+		if !ok {
+			s.innerCursor.Close()
+			s.innerCursor = nil
+			continue
+		}
+
+		item := s.template
+		if s.spray {
+			item.Username = word
+			item.Password = s.current
+			item.Round = s.index / s.perRound
+		} else {
+			item.Username = s.current
+			item.Password = word
+		}
+		item.Extra = copyExtra(s.template.Extra)
+		return &item, true
+	}
+	return nil, false
+}
+
+// nextOuter 切换到外层的下一个词并重新打开内层字典
+func (s *credentialSource) nextOuter() bool {
+	outer, inner := s.users, s.passwords
+	if s.spray {
+		outer, inner = s.passwords, s.users
+	}
+
+	if s.outerCursor == nil {
+		cursor, err := outer.Open()
+		if err != nil {
+			gologger.Error().Msgf("Failed to open %s dictionary: %v", s.dictName(true), err)
+			return false
+		}
+		s.outerCursor = cursor
+	}
+
+	word, ok := s.outerCursor.Next()
+	if !ok {
+		return false
+	}
+
+	cursor, err := inner.Open()
+	if err != nil {
+		gologger.Error().Msgf("Failed to open %s dictionary: %v", s.dictName(false), err)
+		return false
+	}
+	s.current = word
+	s.index++
+	s.innerCursor = cursor
+	return true
+}
+
+// dictName 返回外层或内层字典的名称，用于日志
+func (s *credentialSource) dictName(outer bool) string {
+	if outer != s.spray {
+		return "user"
+	}
+	return "password"
+}
+
+func (s *credentialSource) Total() int64 {
+	return s.total
+}
+
+func (s *credentialSource) Close() error {
+	s.done = true
+	if s.innerCursor != nil {
+		s.innerCursor.Close()
+		s.innerCursor = nil
+	}
+	if s.outerCursor != nil {
+		s.outerCursor.Close()
+		s.outerCursor = nil
+	}
+	return nil
+}
+
+// copyExtra 复制额外参数，避免多个任务项共享同一个 map
+func copyExtra(extra map[string]string) map[string]string {
+	copied := make(map[string]string, len(extra))
+	for k, v := range extra {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,256 @@
+package brute
+
+import (
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// Strategy 凭据调度策略
+type Strategy string
+
+const (
+	StrategyStandard Strategy = "standard" // 逐个用户名尝试所有密码
+	StrategySpray    Strategy = "spray"    // 密码喷洒，每轮对所有用户名尝试同一个密码
+)
+
+// Strategies 返回所有支持的调度策略
+func Strategies() []Strategy {
+	return []Strategy{StrategyStandard, StrategySpray}
+}
+
+// sprayRealm 共享账号锁定策略的一组目标，例如同一域内的多台域控
+// 同一域内的目标按观察窗口同步喷洒：每个目标在一个窗口内只喷洒一轮，所有目标完成当前窗口的一轮后，
+// 再等待一个观察窗口才开启下一个窗口。目标加入调度队列时即成为成员，排队的目标同样会阻塞下一个窗口
+type sprayRealm struct {
+	name    string
+	window  time.Duration
+	mutex   sync.Mutex
+	changed chan struct{}                   // 状态变化时关闭并替换，用于唤醒等待者
+	members map[*targetProcess]*realmMember // 仍在喷洒的目标
+	current int                             // 当前窗口的序号
+	opensAt time.Time                       // 当前窗口的开始时间
+	active  bool                            // 当前窗口内是否已有目标开始喷洒
+	lastEnd time.Time                       // 最近一次有目标结束一轮的时间
+}
+
+// realmMember 喷洒域成员的状态
+type realmMember struct {
+	window int  // 目标所在的窗口
+	done   bool // 目标已完成该窗口内的一轮
+}
+
+// newSprayRealm 创建喷洒域
+func newSprayRealm(name string, window time.Duration) *sprayRealm {
+	return &sprayRealm{
+		name:    name,
+		window:  window,
+		changed: make(chan struct{}),
+		members: make(map[*targetProcess]*realmMember),
+	}
+}
+
+// join 加入喷洒域，已是成员时不做改变
+// 当前窗口内还没有目标开始喷洒时加入当前窗口，否则视为已完成当前窗口，需要等待下一个窗口开启
+func (r *sprayRealm) join(process *targetProcess) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.members[process]; !ok {
+		r.members[process] = &realmMember{window: r.current, done: r.active}
+	}
+}
+
+// leave 离开喷洒域，目标处理完毕、被放弃或挂起后不再阻塞其他目标
+func (r *sprayRealm) leave(process *targetProcess) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	member, ok := r.members[process]
+	if !ok {
+		return
+	}
+	if !member.done && r.active {
+		r.lastEnd = time.Now()
+	}
+	delete(r.members, process)
+	r.notify()
+}
+
+// notify 唤醒所有等待者，调用方需持有锁
+func (r *sprayRealm) notify() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// finish 记录目标已完成所在窗口的一轮
+func (r *sprayRealm) finish(process *targetProcess) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if member, ok := r.members[process]; ok && !member.done {
+		member.done = true
+		r.lastEnd = time.Now()
+		r.notify()
+	}
+}
+
+// reserve 为已完成所在窗口的目标预留下一个窗口，返回目标所在窗口的开始时间
+// 其他目标尚未完成当前窗口时返回 false，调用方需持有锁
+func (r *sprayRealm) reserve(member *realmMember) (time.Time, bool) {
+	if member.done {
+		if member.window == r.current {
+			if !r.completed() {
+				return time.Time{}, false
+			}
+			// 最后一个完成当前窗口的目标开启下一个窗口
+			r.current++
+			r.opensAt = r.lastEnd.Add(r.window)
+			r.active = false
+			r.notify()
+			gologger.Info().Msgf("Spray window %d completed for realm %s, window %d opens in %v",
+				r.current, r.name, r.current+1, max(time.Until(r.opensAt), 0))
+		}
+		member.window, member.done = r.current, false
+	}
+	return r.opensAt, true
+}
+
+// completed 检查所有目标是否都已完成当前窗口，调用方需持有锁
+func (r *sprayRealm) completed() bool {
+	for _, member := range r.members {
+		if !member.done {
+			return false
+		}
+	}
+	return true
+}
+
+// begin 目标所在的窗口已开启时开始喷洒并返回 true
+func (r *sprayRealm) begin(process *targetProcess) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	member, ok := r.members[process]
+	if !ok {
+		return false
+	}
+	opensAt, ok := r.reserve(member)
+	if !ok || time.Now().Before(opensAt) {
+		return false
+	}
+	r.active = true
+	return true
+}
+
+// wait 等待目标所在的窗口开启，目标被放弃或引擎停止时返回 false
+func (r *sprayRealm) wait(process *targetProcess) bool {
+	r.mutex.Lock()
+	for {
+		member, ok := r.members[process]
+		if !ok {
+			r.mutex.Unlock()
+			return true
+		}
+		if opensAt, ok := r.reserve(member); ok {
+			r.mutex.Unlock()
+			return sleepContext(process.ctx, time.Until(opensAt))
+		}
+
+		changed := r.changed
+		r.mutex.Unlock()
+		select {
+		case <-changed:
+		case <-process.ctx.Done():
+			return false
+		}
+		r.mutex.Lock()
+	}
+}
+
+// nextRound 返回距离下一个窗口开启的时间，没有正在等待的窗口时返回 false
+func (r *sprayRealm) nextRound() (time.Duration, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	remaining := time.Until(r.opensAt)
+	return remaining, remaining > 0
+}
+
+// sprayTurn 目标进入喷洒轮次的结果
+type sprayTurn int
+
+const (
+	sprayProceed sprayTurn = iota // 所在窗口已开启，继续喷洒
+	sprayWait                     // 已让出工作槽位，窗口开启后重新排队
+	sprayStop                     // 目标已停止
+)
+
+// joinRealm 目标加入调度队列时加入所属的喷洒域，未指定域时目标所在主机即为一个域
+func (e *Engine) joinRealm(process *targetProcess) {
+	if process.sprayRealm == nil {
+		name := process.realm
+		if name == "" {
+			name = process.host
+		}
+		realm, _ := e.realms.LoadOrStore(name, newSprayRealm(name, e.config.SprayWindow))
+		process.sprayRealm = realm.(*sprayRealm)
+	}
+	process.sprayRealm.join(process)
+}
+
+// leaveRealm 目标结束喷洒，不再阻塞同一域内的其他目标
+func (e *Engine) leaveRealm(process *targetProcess) {
+	if process.sprayRealm != nil {
+		process.sprayRealm.leave(process)
+		process.spraying = false
+	}
+}
+
+// enterSprayRound 在开始新的喷洒轮次前等待当前目标的任务完成，并进入同一域内已开启的窗口
+// 窗口尚未开启时保留任务项并让出工作槽位，等待同一域内的目标完成当前窗口和观察窗口结束后重新排队，
+// 避免等待中的目标占满槽位，使排队的域成员无法完成当前窗口
+func (e *Engine) enterSprayRound(process *targetProcess, item *BruteItem) sprayTurn {
+	process.items.Wait()
+
+	// 上一轮已经找到凭据并停止时不再等待
+	process.mutex.RLock()
+	finished := process.Finished
+	process.mutex.RUnlock()
+	if finished {
+		return sprayStop
+	}
+
+	realm := process.sprayRealm
+	if process.spraying {
+		realm.finish(process)
+		process.spraying = false
+	}
+	if !realm.begin(process) {
+		process.mutex.Lock()
+		process.held = item
+		process.mutex.Unlock()
+
+		// 等待期间仍计入运行中的目标，窗口开启后重新排队
+		e.targetWg.Add(1)
+		go func() {
+			defer e.targetWg.Done()
+			realm.wait(process)
+			e.enqueueTarget(process)
+		}()
+		return sprayWait
+	}
+	process.spraying = true
+	process.round = item.Round
+	return sprayProceed
+}
+
+// NextSprayRound 返回距离最近一个喷洒轮次开始的时间，没有正在等待的轮次时返回 false
+func (e *Engine) NextSprayRound() (time.Duration, bool) {
+	var next time.Duration
+	found := false
+	e.realms.Range(func(key, value interface{}) bool {
+		if remaining, ok := value.(*sprayRealm).nextRound(); ok && (!found || remaining < next) {
+			next = remaining
+			found = true
+		}
+		return true
+	})
+	return next, found
+}
@@ -0,0 +1,150 @@
+package brute
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// latencySampleSize 每个目标保留的耗时样本数，超过后按蓄水池抽样替换
+const latencySampleSize = 1024
+
+// TargetStats 单个目标的统计
+type TargetStats struct {
+	Target          string                `json:"target"`                    // 目标标识，格式为 type:host:port
+	Type            string                `json:"type"`                      // 服务类型
+	Host            string                `json:"host"`                      // 目标地址
+	Port            int                   `json:"port"`                      // 目标端口
+	Attempts        int64                 `json:"attempts"`                  // 得出结果的尝试数
+	Retries         int64                 `json:"retries"`                   // 瞬时错误导致的重试次数
+	Successes       int64                 `json:"successes"`                 // 成功数
+	Failures        map[FailureKind]int64 `json:"failures"`                  // 各失败类型的次数
+	Skipped         int64                 `json:"skipped"`                   // 跳过的任务数
+	AvgLatency      time.Duration         `json:"avg_latency"`               // 平均耗时
+	P95Latency      time.Duration         `json:"p95_latency"`               // 95 分位耗时
+	FirstAttempt    time.Time             `json:"first_attempt"`             // 第一次尝试的开始时间
+	LastAttempt     time.Time             `json:"last_attempt"`              // 最后一次尝试的结束时间
+	Abandoned       string                `json:"abandoned,omitempty"`       // 目标被放弃的原因
+	Unauthenticated bool                  `json:"unauthenticated,omitempty"` // 目标无需认证即可访问
+}
+
+// Failed 返回失败总数
+func (s TargetStats) Failed() int64 {
+	var failed int64
+	for _, count := range s.Failures {
+		failed += count
+	}
+	return failed
+}
+
+// targetStats 目标统计的累加器
+type targetStats struct {
+	mutex        sync.Mutex
+	attempts     int64
+	retries      int64
+	successes    int64
+	failures     map[FailureKind]int64
+	totalLatency time.Duration
+	samples      []time.Duration // 耗时样本，用于计算分位数
+	firstAttempt time.Time
+	lastAttempt  time.Time
+}
+
+// record 记录一个任务的结果
+func (s *targetStats) record(result *BruteResult, finishedAt time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.attempts++
+	s.retries += int64(max(result.Attempts-1, 0))
+	if result.Success {
+		s.successes++
+	} else {
+		if s.failures == nil {
+			s.failures = make(map[FailureKind]int64)
+		}
+		s.failures[result.FailureKind]++
+	}
+
+	s.totalLatency += result.ResponseTime
+	if len(s.samples) < latencySampleSize {
+		s.samples = append(s.samples, result.ResponseTime)
+	} else if i := rand.Int64N(s.attempts); i < latencySampleSize {
+		s.samples[i] = result.ResponseTime
+	}
+
+	startedAt := finishedAt.Add(-result.ResponseTime)
+	if s.firstAttempt.IsZero() || startedAt.Before(s.firstAttempt) {
+		s.firstAttempt = startedAt
+	}
+	if finishedAt.After(s.lastAttempt) {
+		s.lastAttempt = finishedAt
+	}
+}
+
+// snapshot 将累加器的数据写入统计结果
+func (s *targetStats) snapshot(stats *TargetStats) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stats.Attempts = s.attempts
+	stats.Retries = s.retries
+	stats.Successes = s.successes
+	stats.Failures = make(map[FailureKind]int64, len(s.failures))
+	for kind, count := range s.failures {
+		stats.Failures[kind] = count
+	}
+	stats.FirstAttempt = s.firstAttempt
+	stats.LastAttempt = s.lastAttempt
+
+	if s.attempts > 0 {
+		stats.AvgLatency = s.totalLatency / time.Duration(s.attempts)
+	}
+	if len(s.samples) > 0 {
+		sorted := slices.Clone(s.samples)
+		slices.Sort(sorted)
+		stats.P95Latency = sorted[(len(sorted)*95+99)/100-1]
+	}
+}
+
+// stats 返回目标当前的统计
+func (p *targetProcess) stats() TargetStats {
+	stats := TargetStats{
+		Target: p.Target,
+		Type:   p.serviceType,
+		Host:   p.host,
+		Port:   p.port,
+	}
+	p.statistics.snapshot(&stats)
+
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	stats.Unauthenticated = p.unauthenticated
+	if !p.unauthenticated {
+		stats.Abandoned = p.Reason
+	}
+	stats.Skipped = p.skipped
+	if p.Reason != "" {
+		stats.Skipped += max(p.total-p.tracker.count(), 0)
+	}
+	return stats
+}
+
+// GetTargetStats 获取每个目标的统计，按目标添加的顺序排列
+func (e *Engine) GetTargetStats() []TargetStats {
+	e.targetsMutex.RLock()
+	keys := make([]string, 0, e.targets.Len())
+	for element := e.targets.Front(); element != nil; element = element.Next() {
+		keys = append(keys, element.Value.(string))
+	}
+	e.targetsMutex.RUnlock()
+
+	stats := make([]TargetStats, 0, len(keys))
+	for _, key := range keys {
+		if process, ok := e.processes.Load(key); ok {
+			stats = append(stats, process.(*targetProcess).stats())
+		}
+	}
+	return stats
+}
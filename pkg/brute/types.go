@@ -2,6 +2,7 @@ package brute
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -18,18 +19,38 @@ type BruteItem struct {
 	Context            context.Context   `json:"-"`                    // 上下文
 	Timeout            time.Duration     `json:"timeout"`              // 超时时间
 	Extra              map[string]string `json:"extra"`                // 额外参数
+	Enrich             bool              `json:"enrich"`               // 认证成功后采集只读的附加信息，写入结果的 ExtraInfo
+	Seq                int64             `json:"-"`                    // 任务在目标凭据序列中的序号，由引擎分配
+	Round              int               `json:"-"`                    // 密码喷洒模式下任务所属的轮次
+}
+
+// TimeoutContext 返回单次认证使用的上下文，在 Timeout 后超时，引擎停止或放弃目标时一并取消
+// 协议处理器应在该上下文中建立连接和认证，未设置 Context 时以 context.Background 为父上下文
+func (i *BruteItem) TimeoutContext() (context.Context, context.CancelFunc) {
+	parent := i.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	if i.Timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, i.Timeout)
 }
 
 // BruteResult 表示爆破结果
 type BruteResult struct {
-	Item           *BruteItem             `json:"item"`
-	Success        bool                   `json:"success"`
-	Error          error                  `json:"error,omitempty"`
-	ResponseTime   time.Duration          `json:"response_time"`
-	Banner         string                 `json:"banner,omitempty"`
-	Finished       bool                   `json:"finished"`        // 是否完成
-	UserEliminated bool                   `json:"user_eliminated"` // 用户是否被排除
-	ExtraInfo      map[string]interface{} `json:"extra_info,omitempty"`
+	Item            *BruteItem             `json:"item"`
+	Success         bool                   `json:"success"`
+	Error           error                  `json:"error,omitempty"`
+	FailureKind     FailureKind            `json:"failure_kind"` // 失败类型，成功时为 FailureNone
+	ResponseTime    time.Duration          `json:"response_time"`
+	Attempts        int                    `json:"attempts"` // 尝试次数（包含重试）
+	Banner          string                 `json:"banner,omitempty"`
+	Finished        bool                   `json:"finished"`                  // 是否完成
+	UserEliminated  bool                   `json:"user_eliminated"`           // 用户是否被排除
+	Unauthenticated bool                   `json:"unauthenticated,omitempty"` // 未授权探测发现服务无需认证即可访问
+	Unverified      bool                   `json:"unverified,omitempty"`      // 认证成功但随机密码验证没有结论，可能是接受任意密码的目标
+	ExtraInfo       map[string]interface{} `json:"extra_info,omitempty"`
 }
 
 // String 返回结果的字符串表示
@@ -37,10 +58,35 @@ func (r *BruteResult) String() string {
 	status := "FAIL"
 	if r.Success {
 		status = "SUCCESS"
+		if r.Unverified {
+			status = "UNVERIFIED"
+		}
+	}
+	if r.Unauthenticated {
+		if r.Item.Username == "" && r.Item.Password == "" {
+			return fmt.Sprintf("[UNAUTH] %s://%s:%d", r.Item.Type, r.Item.Target, r.Item.Port)
+		}
+		status = "UNAUTH"
 	}
 	return fmt.Sprintf("[%s] %s://%s:%s@%s:%d", status, r.Item.Type, r.Item.Username, r.Item.Password, r.Item.Target, r.Item.Port)
 }
 
+// MarshalJSON 输出结果，错误以字符串形式输出
+func (r *BruteResult) MarshalJSON() ([]byte, error) {
+	type result BruteResult
+	var errMessage string
+	if r.Error != nil {
+		errMessage = r.Error.Error()
+	}
+	return json.Marshal(&struct {
+		*result
+		Error string `json:"error,omitempty"`
+	}{
+		result: (*result)(r),
+		Error:  errMessage,
+	})
+}
+
 // BruteCallback 爆破回调函数类型
 type BruteCallback func(item *BruteItem) *BruteResult
 
@@ -53,56 +99,123 @@ type Config struct {
 	TargetConcurrent int `json:"target_concurrent"` // 目标并发数
 	TaskConcurrent   int `json:"task_concurrent"`   // 任务并发数
 
+	// 目标调度，同时处理的目标有上限，其余目标排队等待且不占用协程
+	MaxActiveTargets int         `json:"max_active_targets"` // 同时处理的目标数上限，0 表示与 TargetConcurrent 相同
+	TargetOrder      TargetOrder `json:"target_order"`       // 目标进入工作槽位的顺序
+	ShuffleSeed      int64       `json:"shuffle_seed"`       // 随机顺序的种子，0 表示每次不同
+
 	// 延迟控制
-	MinDelay time.Duration `json:"min_delay"` // 最小延迟
-	MaxDelay time.Duration `json:"max_delay"` // 最大延迟
+	MinDelay time.Duration `json:"min_delay"` // 同一目标相邻请求之间的最小延迟，未设置全局限流时同时作为全局速率
+	MaxDelay time.Duration `json:"max_delay"` // 最大延迟，大于 MinDelay 时每次随机取值
+
+	// 分层限流，各层同时生效
+	GlobalRateLimit   RateLimit `json:"global_rate_limit"`   // 全局速率
+	HostRateLimit     RateLimit `json:"host_rate_limit"`     // 每个主机的速率，同一主机上的所有服务共享
+	SubnetRateLimit   RateLimit `json:"subnet_rate_limit"`   // 每个子网的速率，IPv4 为 /24，IPv6 为 /64
+	ProtocolRateLimit RateLimit `json:"protocol_rate_limit"` // 每个协议的速率
+
+	// 自适应并发，根据目标的超时和拒绝连接比例调整并发数和延迟
+	Adaptive              bool          `json:"adaptive"`                // 是否启用自适应并发
+	AdaptiveMinConcurrent int           `json:"adaptive_min_concurrent"` // 目标并发数的下限，上限为 TaskConcurrent
+	AdaptiveMaxDelay      time.Duration `json:"adaptive_max_delay"`      // 附加延迟的上限
+	AdaptiveWindow        int           `json:"adaptive_window"`         // 每次调整前观察的尝试次数
+	AdaptiveThreshold     float64       `json:"adaptive_threshold"`      // 超时和拒绝连接的比例超过该值时退避
 
 	// 超时设置
 	Timeout time.Duration `json:"timeout"` // 连接超时
 
 	// 重试设置
-	MaxRetries int `json:"max_retries"` // 最大重试次数
+	MaxRetries      int           `json:"max_retries"`       // 最大重试次数
+	RetryBackoff    time.Duration `json:"retry_backoff"`     // 首次重试的退避时间，之后指数增长
+	MaxRetryBackoff time.Duration `json:"max_retry_backoff"` // 最大退避时间
+
+	// 会话复用，在同一连接上连续尝试多组凭据，省去重复的握手
+	SessionReuse       []string `json:"session_reuse"`        // 启用会话复用的协议，all 表示所有支持的协议
+	MaxSessionAttempts int      `json:"max_session_attempts"` // 每个会话最多尝试的凭据数，0 表示直到服务端断开
 
 	// 停止条件
 	OkToStop           bool `json:"ok_to_stop"`          // 成功后是否停止
-	FinishingThreshold int  `json:"finishing_threshold"` // 完成阈值
+	VerifySuccess      bool `json:"verify_success"`      // 目标首次认证成功时用随机密码验证，随机密码同样成功时放弃目标
+	UnauthProbe        bool `json:"unauth_probe"`        // 调度凭据前探测匿名或无需认证的访问，服务已开放时跳过凭据
+	FinishingThreshold int  `json:"finishing_threshold"` // 连续网络失败达到该次数后放弃目标，0 表示不限制
+
+	// 调度策略
+	Strategy      Strategy      `json:"strategy"`        // 凭据调度策略
+	SprayWindow   time.Duration `json:"spray_window"`    // 密码喷洒每轮之间的观察窗口，避免触发账号锁定
+	SprayPerRound int           `json:"spray_per_round"` // 密码喷洒每轮尝试的密码数
 
 	// 字典设置
 	UserDict     []string `json:"user_dict"`      // 用户字典
 	PassDict     []string `json:"pass_dict"`      // 密码字典
 	UserDictFile string   `json:"user_dict_file"` // 用户字典文件
 	PassDictFile string   `json:"pass_dict_file"` // 密码字典文件
+	Rules        []string `json:"rules"`          // hashcat 风格的密码变形规则，应用到密码字典的每个词
+	RulesFile    string   `json:"rules_file"`     // 密码变形规则文件
+
+	passRules []Rule // 解析后的密码变形规则
 
 	// 其他设置
 	SkipEmptyPassword  bool          `json:"skip_empty_password"`  // 跳过空密码
 	SkipEmptyUsername  bool          `json:"skip_empty_username"`  // 跳过空用户名
 	AllowBlankUsername bool          `json:"allow_blank_username"` // 允许空用户名
 	AllowBlankPassword bool          `json:"allow_blank_password"` // 允许空密码
-	OnlyNeedPassword   bool          `json:"only_need_password"`   // 只需要密码
+	OnlyNeedPassword   bool          `json:"only_need_password"`   // 所有协议都只尝试密码，忽略用户名字典
+	Enrich             bool          `json:"enrich"`               // 认证成功后采集版本、权限等只读信息
 	CustomCallback     BruteCallback `json:"-"`                    // 自定义回调
+	// CredentialProvider 按协议提供成对尝试的凭据，提供了凭据的目标不再使用用户名和密码字典，凭据中的空字段不会被跳过
+	CredentialProvider CredentialProvider `json:"-"`
 	// 显示进度
 	ShowProgress bool `json:"show_progress"` // 是否显示进度
 
 	// 扫描范围
 	PortRange    string `json:"port_range"`    // 端口范围
 	ExcludePorts []int  `json:"exclude_ports"` // 排除端口
+
+	// 存活探测
+	PreCheck           bool          `json:"pre_check"`            // 调度凭据前探测目标是否可达
+	PreCheckTimeout    time.Duration `json:"pre_check_timeout"`    // 探测超时，与认证超时相互独立
+	PreCheckConcurrent int           `json:"pre_check_concurrent"` // 探测并发数
+
+	// 指标导出
+	MetricsListen string `json:"metrics_listen"` // Prometheus 指标监听地址，例如 127.0.0.1:9090，为空时不启用
+
+	// 断点续传
+	CheckpointFile     string        `json:"checkpoint_file"`     // 检查点文件
+	CheckpointInterval time.Duration `json:"checkpoint_interval"` // 检查点写入间隔
 }
 
 // DefaultConfig 返回默认配置
 func DefaultConfig() *Config {
 	return &Config{
-		TargetConcurrent:   10,                      // 降低默认并发数，避免过度并发
-		TaskConcurrent:     5,                       // 单个目标的任务并发数
-		MinDelay:           time.Millisecond * 200,  // 增加默认延迟，避免过快请求
-		MaxDelay:           time.Millisecond * 1000, // 最大延迟
-		Timeout:            time.Second * 10,        // 连接超时
-		MaxRetries:         3,                       // 最大重试次数
-		OkToStop:           false,                   // 成功后不自动停止
-		FinishingThreshold: 10,                      // 完成阈值
-		SkipEmptyPassword:  true,                    // 跳过空密码
-		SkipEmptyUsername:  true,                    // 跳过空用户名
-		OnlyNeedPassword:   false,
-		PortRange:          "",
-		ExcludePorts:       []int{},
+		TargetConcurrent:      10,                      // 降低默认并发数，避免过度并发
+		TaskConcurrent:        5,                       // 单个目标的任务并发数
+		TargetOrder:           TargetOrderInput,        // 按添加顺序调度目标
+		MinDelay:              time.Millisecond * 200,  // 增加默认延迟，避免过快请求
+		MaxDelay:              time.Millisecond * 1000, // 最大延迟
+		Timeout:               time.Second * 10,        // 连接超时
+		Adaptive:              false,                   // 自适应并发默认关闭，命令行默认开启
+		AdaptiveMinConcurrent: 1,                       // 最低单并发
+		AdaptiveMaxDelay:      time.Second * 5,         // 最大附加延迟
+		AdaptiveWindow:        10,                      // 每10次尝试评估一次
+		AdaptiveThreshold:     0.3,                     // 超过30%的尝试超时或被拒绝时退避
+		MaxRetries:            3,                       // 最大重试次数
+		RetryBackoff:          time.Millisecond * 500,  // 首次重试退避时间
+		MaxRetryBackoff:       time.Second * 10,        // 最大退避时间
+		OkToStop:              false,                   // 成功后不自动停止
+		VerifySuccess:         false,                   // 随机密码验证会多一次登录，默认关闭，命令行默认开启
+		UnauthProbe:           false,                   // 未授权访问探测默认关闭，命令行默认开启
+		FinishingThreshold:    10,                      // 连续10次网络失败后放弃目标
+		Strategy:              StrategyStandard,        // 逐个用户名尝试所有密码
+		SprayWindow:           time.Minute * 30,        // 喷洒观察窗口
+		SprayPerRound:         1,                       // 每轮喷洒一个密码
+		SkipEmptyPassword:     true,                    // 跳过空密码
+		SkipEmptyUsername:     true,                    // 跳过空用户名
+		OnlyNeedPassword:      false,
+		PortRange:             "",
+		ExcludePorts:          []int{},
+		PreCheck:              false,            // 存活探测默认关闭，命令行默认开启
+		PreCheckTimeout:       time.Second * 3,  // 探测超时
+		PreCheckConcurrent:    50,               // 探测并发数
+		CheckpointInterval:    time.Second * 30, // 每30秒写入一次检查点
 	}
 }
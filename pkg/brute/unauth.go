@@ -0,0 +1,109 @@
+package brute
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// UnauthProber 支持未授权访问探测的协议处理器
+type UnauthProber interface {
+	ProtocolHandler
+	// ProbeUnauth 检查目标是否无需认证即可访问，例如 Redis 未设置密码或 FTP 允许匿名登录
+	// 结果成功表示服务已开放，实际使用的凭据（如 anonymous 或 SNMP community）记录在结果的任务项中
+	ProbeUnauth(item *BruteItem) *BruteResult
+}
+
+// unauthHandler 为协议处理器附加未授权访问探测
+type unauthHandler struct {
+	ProtocolHandler
+	probe BruteCallback
+}
+
+func (h *unauthHandler) ProbeUnauth(item *BruteItem) *BruteResult {
+	return h.probe(item)
+}
+
+// unauthSessionHandler 为支持会话复用的协议处理器附加未授权访问探测
+type unauthSessionHandler struct {
+	SessionProtocol
+	probe BruteCallback
+}
+
+func (h *unauthSessionHandler) ProbeUnauth(item *BruteItem) *BruteResult {
+	return h.probe(item)
+}
+
+// WithUnauthProbe 为协议处理器附加未授权访问探测，保留处理器对会话复用的支持
+func WithUnauthProbe(handler ProtocolHandler, probe BruteCallback) ProtocolHandler {
+	if session, ok := handler.(SessionProtocol); ok {
+		return &unauthSessionHandler{SessionProtocol: session, probe: probe}
+	}
+	return &unauthHandler{ProtocolHandler: handler, probe: probe}
+}
+
+// SupportsUnauthProbe 返回协议是否支持未授权访问探测
+func SupportsUnauthProbe(protocol string) bool {
+	handler, exists := GetProtocol(protocol)
+	if !exists {
+		return false
+	}
+	_, ok := handler.(UnauthProber)
+	return ok
+}
+
+// probeUnauth 在调度凭据之前检查目标是否无需认证即可访问，返回 true 表示服务已开放，不再尝试凭据
+func (e *Engine) probeUnauth(process *targetProcess) bool {
+	// 自定义回调替代了所有协议处理器，不进行探测
+	if e.config.CustomCallback != nil {
+		return false
+	}
+	handler, exists := GetProtocol(process.serviceType)
+	if !exists {
+		return false
+	}
+	prober, ok := handler.(UnauthProber)
+	if !ok || !e.throttle(process) {
+		return false
+	}
+
+	item := &BruteItem{
+		Type:    process.serviceType,
+		Target:  process.host,
+		Port:    process.port,
+		Context: process.ctx,
+		Timeout: e.config.Timeout,
+		Enrich:  e.config.Enrich,
+	}
+	startTime := time.Now()
+	result := prober.ProbeUnauth(item)
+	result.ResponseTime = time.Since(startTime)
+	result.Attempts = 1
+	result.normalize()
+	if !result.Success {
+		gologger.Debug().Msgf("Target %s requires authentication (%s)", process.Target, result.FailureKind)
+		return false
+	}
+	result.Unauthenticated = true
+
+	process.mutex.Lock()
+	process.Reason = "no authentication required"
+	process.Finished = true
+	process.unauthenticated = true
+	process.mutex.Unlock()
+	process.statistics.record(result, time.Now())
+	atomic.AddInt64(&e.unauthTargets, 1)
+
+	gologger.Info().Msgf("Target %s allows access without authentication, skipping credentials", process.Target)
+	if e.resultCallback != nil {
+		e.resultCallback(result)
+	}
+	e.publish(Event{Type: EventUnauthenticated, Target: process.Target, Result: result})
+	return true
+}
+
+// GetUnauthTargets 获取无需认证即可访问的目标数
+func (e *Engine) GetUnauthTargets() int64 {
+	return atomic.LoadInt64(&e.unauthTargets)
+}
@@ -0,0 +1,62 @@
+package brute
+
+import (
+	"crypto/rand"
+	"sync/atomic"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// verifyVerdict 随机密码验证的结论
+type verifyVerdict int
+
+const (
+	verifyUnknown   verifyVerdict = iota // 验证没有得到结论，例如重试后仍然超时
+	verifyGenuine                        // 随机密码被拒绝，认证成功可信
+	verifyAcceptAny                      // 随机密码同样成功，认证成功不可信
+)
+
+// verifySuccess 验证目标是否接受任意密码
+// 目标认证成功时以相同用户名和随机密码再认证一次，随机密码同样成功说明认证结果不可信，
+// 例如蜜罐、不提示登录的 telnet 或总是返回 200 的 HTTP 服务，此时放弃目标。
+// 验证遇到瞬时错误时与普通任务一样按指数退避重试，只有随机密码明确被拒绝才认为成功可信。
+// 得到结论后同一目标不再验证，并发的成功结果等待验证完成后使用同一结论；
+// 没有结论时返回最后一次验证的结果，下一次认证成功时重新验证
+func (e *Engine) verifySuccess(item *BruteItem, process *targetProcess) (verifyVerdict, *BruteResult) {
+	process.verifyMutex.Lock()
+	defer process.verifyMutex.Unlock()
+	if process.verified != verifyUnknown {
+		return process.verified, nil
+	}
+
+	probe := *item
+	probe.Password = rand.Text()
+	probe.Enrich = false
+	var result *BruteResult
+	for attempt := 1; ; attempt++ {
+		if !e.throttle(process) {
+			return verifyUnknown, NewResult(&probe).Fail(FailureCanceled, process.ctx.Err())
+		}
+		result = e.executeItem(&probe, process)
+		result.normalize()
+		if result.Success || attempt > e.config.MaxRetries || !result.FailureKind.Retryable() {
+			break
+		}
+		atomic.AddInt64(&e.retriedItems, 1)
+		if !sleepContext(process.ctx, e.retryBackoff(attempt)) {
+			return verifyUnknown, NewResult(&probe).Fail(FailureCanceled, process.ctx.Err())
+		}
+	}
+
+	switch {
+	case result.Success:
+		process.verified = verifyAcceptAny
+		e.abandonTarget(process, "accepts a random password, likely a honeypot or accept-anything service")
+	case result.FailureKind == FailureAuthRejected:
+		gologger.Debug().Msgf("Target %s rejected a random password, success is genuine", process.Target)
+		process.verified = verifyGenuine
+	default:
+		gologger.Debug().Msgf("Random password check on target %s was inconclusive (%s): %v", process.Target, result.FailureKind, result.Error)
+	}
+	return process.verified, result
+}
@@ -0,0 +1,125 @@
+// Package packs 内置的默认凭据包，按协议和产品整理，凭据成对尝试而不是交叉组合
+package packs
+
+import (
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed packs.yaml
+var packsData []byte
+
+// Pack 默认凭据包
+type Pack struct {
+	Name        string             `json:"name"`        // 凭据包名称
+	Product     string             `json:"product"`     // 适用的产品
+	Protocols   []string           `json:"protocols"`   // 适用的协议
+	Credentials []brute.Credential `json:"credentials"` // 成对尝试的凭据
+}
+
+// packEntry 凭据包数据中的一项，凭据为 用户名:密码 形式
+type packEntry struct {
+	Name        string   `yaml:"name"`
+	Product     string   `yaml:"product"`
+	Protocols   []string `yaml:"protocols"`
+	Credentials []string `yaml:"credentials"`
+}
+
+var (
+	loadOnce sync.Once
+	packs    []*Pack
+)
+
+// load 解析内置凭据包，数据随程序编译，格式错误属于编程错误
+func load() []*Pack {
+	loadOnce.Do(func() {
+		parsed, err := parse(packsData)
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in credential packs: %v", err))
+		}
+		packs = parsed
+	})
+	return packs
+}
+
+// parse 解析凭据包数据，凭据以第一个冒号分隔用户名和密码
+func parse(data []byte) ([]*Pack, error) {
+	var entries []packEntry
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	parsed := make([]*Pack, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Name == "" || slices.ContainsFunc(parsed, func(pack *Pack) bool { return pack.Name == entry.Name }) {
+			return nil, fmt.Errorf("missing or duplicate pack name %q", entry.Name)
+		}
+		pack := &Pack{Name: entry.Name, Product: entry.Product, Protocols: entry.Protocols}
+		for _, credential := range entry.Credentials {
+			username, password, ok := strings.Cut(credential, ":")
+			if !ok {
+				return nil, fmt.Errorf("pack %s: credential %q is not in user:pass form", entry.Name, credential)
+			}
+			pack.Credentials = append(pack.Credentials, brute.Credential{Username: username, Password: password})
+		}
+		parsed = append(parsed, pack)
+	}
+	return parsed, nil
+}
+
+// List 返回所有内置凭据包
+func List() []*Pack {
+	return slices.Clone(load())
+}
+
+// Get 按名称获取凭据包
+func Get(name string) (*Pack, bool) {
+	return lo.Find(load(), func(pack *Pack) bool {
+		return strings.EqualFold(pack.Name, name)
+	})
+}
+
+// ForProtocol 返回适用于协议的凭据包，协议别名统一为协议名后匹配
+func ForProtocol(protocol string) []*Pack {
+	protocol = resolve(protocol)
+	return lo.Filter(load(), func(pack *Pack, _ int) bool {
+		return slices.ContainsFunc(pack.Protocols, func(name string) bool {
+			return resolve(name) == protocol
+		})
+	})
+}
+
+// Credentials 返回适用于协议的所有凭据，多个凭据包按顺序合并并去重
+// 可以直接作为 brute.CredentialProvider 使用
+func Credentials(protocol string) []brute.Credential {
+	return Merge(ForProtocol(protocol))
+}
+
+// Merge 按顺序合并多个凭据包的凭据并去重
+func Merge(selected []*Pack) []brute.Credential {
+	return lo.Uniq(lo.FlatMap(selected, func(pack *Pack, _ int) []brute.Credential {
+		return pack.Credentials
+	}))
+}
+
+// Supported 返回凭据包中已注册处理器的协议，其余协议的凭据只能导出使用
+func (p *Pack) Supported() []string {
+	return lo.Filter(p.Protocols, func(name string, _ int) bool {
+		_, ok := brute.ResolveProtocol(name)
+		return ok
+	})
+}
+
+// resolve 将协议别名统一为协议名，未注册的协议保持原样
+func resolve(protocol string) string {
+	if name, ok := brute.ResolveProtocol(protocol); ok {
+		return name
+	}
+	return strings.ToLower(protocol)
+}
@@ -1,33 +1,38 @@
 package protocols
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// AMQPBrute AMQP爆破
 func AMQPBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
+	result := brute.NewResult(item)
+	// AMQP 没有匿名访问，不带凭据的地址会被客户端库替换为 guest:guest，默认账号通过字典尝试
+	if item.Username == "" || item.Password == "" {
+		return result.Unsupported("AMQP requires both username and password")
 	}
-	// 创建带超时的 context
-	var target string
-	if item.Username == "" && item.Password == "" {
-		target = fmt.Sprintf("amqp://%s:%d", item.Target, item.Port)
-	} else if item.Password != "" && item.Username != "" {
-		target = fmt.Sprintf("amqp://%s:%s@%s:%d", item.Username, item.Password, item.Target, item.Port)
-	} else {
-		return result
-	}
-	conn, err := amqp.Dial(target)
+	target := fmt.Sprintf("amqp://%s:%s@%s:%d", item.Username, item.Password, item.Target, item.Port)
+	ctx, cancel := item.TimeoutContext()
+	defer cancel()
+
+	// 连接绑定到任务项的上下文，握手阻塞时随上下文结束关闭
+	conn, err := amqp.DialConfig(target, amqp.Config{
+		Locale: "en_US",
+		Dial:   utils.NewDialer(ctx, item.Timeout).Dial,
+	})
 	if err != nil {
-		result.Error = err
-		return result
+		// 服务端以 ACCESS_REFUSED 拒绝错误的凭据
+		var amqpErr *amqp.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp.AccessRefused {
+			return result.AuthRejected(err)
+		}
+		return result.FailWithError(err)
 	}
 	defer conn.Close()
-	result.Success = true
-	result.Banner = "AMQP authentication successful"
-	return result
+	return result.Succeed("AMQP authentication successful")
 }
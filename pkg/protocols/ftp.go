@@ -2,102 +2,107 @@ package protocols
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/textproto"
 	"time"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
 	"github.com/jlaffaye/ftp"
+	"github.com/projectdiscovery/gologger"
 )
 
-// FTPBrute FTP爆破，支持 context 超时控制
+// FTPBrute FTP爆破，连接在任务项的上下文中建立，超时或取消时立即关闭
 func FTPBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
 	if item.Username == "" || item.Password == "" {
-		return result
+		return brute.NewResult(item).Unsupported("FTP requires both username and password")
 	}
+	return authenticateOnce(item, newFTPSession)
+}
 
-	// 创建带超时的 context
-	ctx, cancel := context.WithTimeout(context.Background(), item.Timeout)
-	defer cancel()
+// ftpSession FTP 会话，登录失败后可以在同一连接上继续发送 USER/PASS
+type ftpSession struct {
+	ctx    context.Context
+	dialer *sessionDialer
+	conn   *ftp.ServerConn
+}
 
-	// 使用 channel 来传递结果，支持 context 取消
-	type ftpResult struct {
-		success bool
-		err     error
-		banner  string
+// newFTPSession 连接 FTP 服务器，被动模式的数据连接同样绑定到上下文
+func newFTPSession(ctx context.Context, item *brute.BruteItem) (brute.Session, error) {
+	dialer := newSessionDialer(ctx, item.Timeout)
+	target := fmt.Sprintf("%s:%d", item.Target, item.Port)
+	c, err := ftp.Dial(target,
+		ftp.DialWithTimeout(item.Timeout),
+		ftp.DialWithDialFunc(dialer.Dial))
+	if err != nil {
+		return nil, fmt.Errorf("FTP dial failed: %w", err)
 	}
+	return &ftpSession{ctx: ctx, dialer: dialer, conn: c}, nil
+}
 
-	resultChan := make(chan ftpResult, 1)
+func (s *ftpSession) Accepts(item *brute.BruteItem) bool {
+	return true
+}
 
-	// 在 goroutine 中执行 FTP 操作
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				resultChan <- ftpResult{
-					success: false,
-					err:     fmt.Errorf("FTP operation panic: %v", r),
-				}
-			}
-		}()
+func (s *ftpSession) Authenticate(item *brute.BruteItem) (*brute.BruteResult, bool) {
+	result := brute.NewResult(item)
+	if item.Username == "" || item.Password == "" {
+		return result.Unsupported("FTP requires both username and password"), true
+	}
+	s.dialer.extend(item.Timeout)
 
-		target := fmt.Sprintf("%s:%d", item.Target, item.Port)
+	// 尝试登录
+	if err := s.conn.Login(item.Username, item.Password); err != nil {
+		result = failWithContext(s.ctx, result, classifyFTPError(err), fmt.Errorf("FTP login failed: %w", err))
+		return result, result.FailureKind == brute.FailureAuthRejected
+	}
 
-		// 连接 FTP 服务器
+	// 登录成功，验证连接状态
+	dir, err := s.conn.CurrentDir()
+	if err != nil {
+		return failWithContext(s.ctx, result, brute.FailureProtocolError, fmt.Errorf("FTP connection verification failed: %w", err)), false
+	}
 
-		c, err := ftp.Dial(target, ftp.DialWithTimeout(item.Timeout))
-		if err != nil {
-			resultChan <- ftpResult{
-				success: false,
-				err:     fmt.Errorf("FTP dial failed: %w", err),
-			}
-			return
+	result.Succeed("FTP login successful")
+	if item.Enrich {
+		result.SetExtra("working_dir", dir)
+		if system, err := ftpSystemType(s.dialer.conn); err != nil {
+			gologger.Debug().Msgf("FTP enrichment failed for %s: %v", item.Target, err)
+		} else {
+			result.SetExtra("system_type", system)
 		}
-		defer c.Quit()
+	}
+	return result, false
+}
 
-		// 尝试登录
-		err = c.Login(item.Username, item.Password)
-		if err != nil {
-			resultChan <- ftpResult{
-				success: false,
-				err:     fmt.Errorf("FTP login failed: %w", err),
-			}
-			return
-		}
+// ftpSystemType 发送 SYST 获取服务端的系统类型，ftp 库没有提供该命令
+// 命令直接写在控制连接上，认证成功后会话不再使用，不会打乱库的读写状态
+func ftpSystemType(conn net.Conn) (string, error) {
+	tp := textproto.NewConn(conn)
+	if _, err := tp.Cmd("SYST"); err != nil {
+		return "", err
+	}
+	_, message, err := tp.ReadResponse(ftp.StatusName)
+	return message, err
+}
 
-		// 登录成功，验证连接状态
-		_, err = c.CurrentDir()
-		if err != nil {
-			resultChan <- ftpResult{
-				success: false,
-				err:     fmt.Errorf("FTP connection verification failed: %w", err),
-			}
-			return
-		}
+func (s *ftpSession) Close() error {
+	s.dialer.extend(time.Second)
+	return s.conn.Quit()
+}
 
-		resultChan <- ftpResult{
-			success: true,
-			banner:  "FTP login successful",
+// classifyFTPError 根据 FTP 响应码区分认证失败
+func classifyFTPError(err error) brute.FailureKind {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		switch protoErr.Code {
+		case ftp.StatusNotLoggedIn, ftp.StatusBadArguments:
+			return brute.FailureAuthRejected
+		case ftp.StatusNotAvailable:
+			return brute.FailureConnReset
 		}
-	}()
-
-	// 等待结果或超时
-	select {
-	case ftpRes := <-resultChan:
-		result.Success = ftpRes.success
-		result.Error = ftpRes.err
-		result.Banner = ftpRes.banner
-		return result
-
-	case <-ctx.Done():
-		result.Error = fmt.Errorf("FTP operation timeout after %v: %w", item.Timeout, ctx.Err())
-		return result
-
-	case <-time.After(item.Timeout + time.Second*2):
-		// 额外的安全超时，防止 context 失效
-		result.Error = fmt.Errorf("FTP operation hard timeout after %v", item.Timeout+time.Second*2)
-		return result
+		return brute.FailureProtocolError
 	}
+	return brute.ClassifyError(err)
 }
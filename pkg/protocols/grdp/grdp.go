@@ -1,10 +1,10 @@
 package grdp
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
-	"net"
 	"os"
 	"sync"
 	"time"
@@ -18,6 +18,7 @@ import (
 	"github.com/XTeam-Wing/x-crack/pkg/protocols/grdp/protocol/t125"
 	"github.com/XTeam-Wing/x-crack/pkg/protocols/grdp/protocol/tpkt"
 	"github.com/XTeam-Wing/x-crack/pkg/protocols/grdp/protocol/x224"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
 )
 
 const (
@@ -26,7 +27,8 @@ const (
 )
 
 type Client struct {
-	Host string // ip:port
+	Host string          // ip:port
+	ctx  context.Context // 连接的上下文，结束时关闭连接
 	tpkt *tpkt.TPKT
 	x224 *x224.X224
 	mcs  *t125.MCSClient
@@ -41,13 +43,20 @@ func NewClient(host string, logLevel glog.LEVEL) *Client {
 	glog.SetLogger(logger)
 	return &Client{
 		Host: host,
+		ctx:  context.Background(),
 	}
 }
 
+// WithContext 设置连接的上下文，上下文取消或超时时关闭连接，阻塞的登录随之返回
+func (g *Client) WithContext(ctx context.Context) *Client {
+	g.ctx = ctx
+	return g
+}
+
 func (g *Client) loginForSSL(domain, user, pwd string) error {
-	conn, err := net.DialTimeout("tcp", g.Host, 3*time.Second)
+	conn, err := utils.DialContext(g.ctx, "tcp", g.Host, 3*time.Second)
 	if err != nil {
-		return fmt.Errorf("[dial err] %v", err)
+		return fmt.Errorf("[dial err] %w", err)
 	}
 	defer conn.Close()
 	glog.Info(conn.LocalAddr().String())
@@ -108,9 +117,9 @@ func (g *Client) loginForSSL(domain, user, pwd string) error {
 }
 
 func (g *Client) loginForRDP(domain, user, pwd string) error {
-	conn, err := net.DialTimeout("tcp", g.Host, 3*time.Second)
+	conn, err := utils.DialContext(g.ctx, "tcp", g.Host, 3*time.Second)
 	if err != nil {
-		return fmt.Errorf("[dial err] %v", err)
+		return fmt.Errorf("[dial err] %w", err)
 	}
 	defer conn.Close()
 	glog.Info(conn.LocalAddr().String())
@@ -171,8 +180,11 @@ func (g *Client) loginForRDP(domain, user, pwd string) error {
 		}
 	})
 
-	//wait 2 Second
-	time.Sleep(time.Second * 3)
+	//wait 3 Second
+	select {
+	case <-time.After(time.Second * 3):
+	case <-g.ctx.Done():
+	}
 	if breakFlag == false {
 		breakFlag = true
 		wg.Done()
@@ -207,30 +219,35 @@ func Login(target, domain, username, password string) error {
 }
 
 func LoginForSSL(target, domain, username, password string) error {
-	var err error
-	g := NewClient(target, glog.NONE)
+	return LoginForSSLContext(context.Background(), target, domain, username, password)
+}
+
+// LoginForSSLContext 在上下文中使用 SSL 协议登录
+func LoginForSSLContext(ctx context.Context, target, domain, username, password string) error {
+	g := NewClient(target, glog.NONE).WithContext(ctx)
 	//SSL协议登录测试
-	err = g.loginForSSL(domain, username, password)
-	if err == nil {
-		return nil
-	}
-	return err
+	return g.loginForSSL(domain, username, password)
 }
 
 func LoginForRDP(target, domain, username, password string) error {
-	var err error
-	g := NewClient(target, glog.NONE)
-	//SSL协议登录测试
-	err = g.loginForRDP(domain, username, password)
-	if err == nil {
-		return nil
-	}
-	return err
+	return LoginForRDPContext(context.Background(), target, domain, username, password)
+}
+
+// LoginForRDPContext 在上下文中使用 RDP 协议登录
+func LoginForRDPContext(ctx context.Context, target, domain, username, password string) error {
+	g := NewClient(target, glog.NONE).WithContext(ctx)
+	//RDP协议登录测试
+	return g.loginForRDP(domain, username, password)
 }
 
 func VerifyProtocol(target string) string {
+	return VerifyProtocolContext(context.Background(), target)
+}
+
+// VerifyProtocolContext 在上下文中检测服务端使用的协议
+func VerifyProtocolContext(ctx context.Context, target string) string {
 	var err error
-	err = LoginForSSL(target, "", "administrator", "test")
+	err = LoginForSSLContext(ctx, target, "", "administrator", "test")
 	if err == nil {
 		return PROTOCOL_SSL
 	}
@@ -5,47 +5,49 @@ import (
 	"net/http"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
 )
 
 // HTTPBrute HTTP基础认证爆破
 func HTTPBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
+	result := brute.NewResult(item)
 	if item.Username == "" || item.Password == "" {
-		return result
+		return result.Unsupported("HTTP Basic Auth requires both username and password")
 	}
 	timeout := item.Timeout
+	ctx, cancel := item.TimeoutContext()
+	defer cancel()
+
 	client := &http.Client{
 		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:       utils.NewDialer(ctx, timeout).DialContext,
+			DisableKeepAlives: true,
+		},
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
 	}
 
 	url := fmt.Sprintf("http://%s:%d/", item.Target, item.Port)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		result.Error = err
-		return result
+		return result.Fail(brute.FailureUnknown, err)
 	}
 
 	req.SetBasicAuth(item.Username, item.Password)
 
 	resp, err := client.Do(req)
 	if err != nil {
-		result.Error = err
-		return result
+		return result.FailWithError(err)
 	}
 	defer resp.Body.Close()
 
 	// HTTP 200/302/3xx 认为成功，401/403 认为失败
 	if resp.StatusCode != 401 && resp.StatusCode != 403 {
-		result.Success = true
-		result.Banner = fmt.Sprintf("HTTP Basic Auth successful (Status: %d)", resp.StatusCode)
+		result.Succeed(fmt.Sprintf("HTTP Basic Auth successful (Status: %d)", resp.StatusCode))
 	} else {
-		result.Error = fmt.Errorf("HTTP Basic Auth failed (Status: %d)", resp.StatusCode)
+		result.AuthRejected(fmt.Errorf("HTTP Basic Auth failed (Status: %d)", resp.StatusCode))
 	}
 
 	return result
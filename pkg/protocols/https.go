@@ -6,22 +6,25 @@ import (
 	"net/http"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
 )
 
 // HTTPSBrute HTTPS基础认证爆破
 func HTTPSBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
+	result := brute.NewResult(item)
 	if item.Username == "" || item.Password == "" {
-		return result
+		return result.Unsupported("HTTPS Basic Auth requires both username and password")
 	}
 	timeout := item.Timeout
+	ctx, cancel := item.TimeoutContext()
+	defer cancel()
+
 	client := &http.Client{
 		Timeout: timeout,
 		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			DialContext:       utils.NewDialer(ctx, timeout).DialContext,
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
 		},
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
@@ -29,27 +32,24 @@ func HTTPSBrute(item *brute.BruteItem) *brute.BruteResult {
 	}
 
 	url := fmt.Sprintf("https://%s:%d/", item.Target, item.Port)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		result.Error = err
-		return result
+		return result.Fail(brute.FailureUnknown, err)
 	}
 
 	req.SetBasicAuth(item.Username, item.Password)
 
 	resp, err := client.Do(req)
 	if err != nil {
-		result.Error = err
-		return result
+		return result.FailWithError(err)
 	}
 	defer resp.Body.Close()
 
 	// HTTP 200/302/3xx 认为成功，401/403 认为失败
 	if resp.StatusCode != 401 && resp.StatusCode != 403 {
-		result.Success = true
-		result.Banner = fmt.Sprintf("HTTPS Basic Auth successful (Status: %d)", resp.StatusCode)
+		result.Succeed(fmt.Sprintf("HTTPS Basic Auth successful (Status: %d)", resp.StatusCode))
 	} else {
-		result.Error = fmt.Errorf("HTTPS Basic Auth failed (Status: %d)", resp.StatusCode)
+		result.AuthRejected(fmt.Errorf("HTTPS Basic Auth failed (Status: %d)", resp.StatusCode))
 	}
 
 	return result
@@ -4,54 +4,64 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
 )
 
 // HTTPSProxyBrute HTTP代理爆破
 func HTTPSProxyBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
+	result := brute.NewResult(item)
 	var httpProxyAddress string
 	if item.Username == "" && item.Password == "" {
 		httpProxyAddress = fmt.Sprintf("https://%s:%d", item.Target, item.Port)
 	} else if item.Password != "" && item.Username != "" {
 		httpProxyAddress = fmt.Sprintf("https://%s:%s@%s:%d", item.Username, item.Password, item.Target, item.Port)
 	} else {
-		return result
+		return result.Unsupported("proxy authentication requires both username and password")
 	}
 	// 实现HTTP代理的验证逻辑
 	proxyURL, err := url.Parse(httpProxyAddress)
 	if err != nil {
-		result.Error = err
-		return result
+		return result.Fail(brute.FailureUnknown, err)
 	}
+	// 代理连接绑定到任务项的上下文，超时或取消时立即关闭
+	ctx, cancel := item.TimeoutContext()
+	defer cancel()
+
 	httpTransport := &http.Transport{
-		Proxy: http.ProxyURL(proxyURL),
+		Proxy:             http.ProxyURL(proxyURL),
+		DialContext:       utils.NewDialer(ctx, item.Timeout).DialContext,
+		DisableKeepAlives: true,
 	}
 	client := &http.Client{
 		Transport: httpTransport,
 	}
 
 	// 例如使用http.Client发送请求，设置代理地址等
-	req, err := http.NewRequest("GET", "https://baidu.com", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://baidu.com", nil)
 	if err != nil {
-		result.Error = err
-		return result
+		return result.Fail(brute.FailureUnknown, err)
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		result.Error = err
-		return result
+		// 代理拒绝认证时 CONNECT 返回 407
+		if strings.Contains(err.Error(), http.StatusText(http.StatusProxyAuthRequired)) {
+			return result.AuthRejected(err)
+		}
+		return result.FailWithError(err)
 	}
 	defer resp.Body.Close()
 
 	// 检查响应状态
-	if resp.StatusCode == http.StatusOK {
-		result.Success = true
-		result.Banner = "HTTP Proxy authentication successful"
+	switch resp.StatusCode {
+	case http.StatusOK:
+		result.Succeed("HTTP Proxy authentication successful")
+	case http.StatusProxyAuthRequired:
+		result.AuthRejected(fmt.Errorf("proxy authentication failed (Status: %d)", resp.StatusCode))
+	default:
+		result.Fail(brute.FailureProtocolError, fmt.Errorf("unexpected proxy response (Status: %d)", resp.StatusCode))
 	}
 
 	return result
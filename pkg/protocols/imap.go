@@ -1,28 +1,201 @@
 package protocols
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net/textproto"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
 	"github.com/yaklang/yaklang/common/utils/bruteutils"
 )
 
-// IMAPBrute IMAP爆破
+// IMAPBrute IMAP爆破，连接在任务项的上下文中建立，超时或取消时立即关闭
 func IMAPBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
+	result := brute.NewResult(item)
+	if item.Username == "" {
+		return result.Unsupported("IMAP requires a username")
+	}
+
+	ctx, cancel := item.TimeoutContext()
+	defer cancel()
+
+	// 与 bruteutils.IMAPAuth 相同的流程，连接改为绑定到上下文
+	conn, err := utils.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", item.Target, item.Port), item.Timeout)
+	if err != nil {
+		return result.FailWithError(fmt.Errorf("IMAP dial failed: %w", err))
+	}
+	client := bruteutils.NewIMAPClient(conn, item.Target)
+	defer client.Close()
+	if !client.IsIMAP() || client.GetCap() != nil {
+		return failWithContext(ctx, result, brute.FailureProtocolError, errors.New("not an imap or service shutdown"))
+	}
+
+	ok, err := client.StartIMAP(item.Username, item.Password)
+	if err != nil {
+		return failWithContext(ctx, result, classifyMailError(err), err)
+	}
+	if !ok {
+		return failWithContext(ctx, result, brute.FailureAuthRejected, nil)
+	}
+	return result.Succeed("IMAP login successful")
+}
+
+// imapSession IMAP 会话，使用带标签的 LOGIN 命令认证
+// 认证被拒绝（NO）后服务端仍处于未认证状态，可以继续尝试，服务端发送 BYE 时会话不再使用
+// 未启用会话复用时 IMAPBrute 仍通过 bruteutils 按服务端声明的 AUTHENTICATE 机制认证
+type imapSession struct {
+	ctx    context.Context
+	dialer *sessionDialer
+	text   *textproto.Conn
+	tag    int
+}
+
+// newIMAPSession 连接 IMAP 服务器，服务器支持时升级到 STARTTLS
+func newIMAPSession(ctx context.Context, item *brute.BruteItem) (brute.Session, error) {
+	dialer := newSessionDialer(ctx, item.Timeout)
+	conn, err := dialer.Dial("tcp", fmt.Sprintf("%s:%d", item.Target, item.Port))
+	if err != nil {
+		return nil, fmt.Errorf("IMAP dial failed: %w", err)
+	}
+	dialer.extend(item.Timeout)
+
+	s := &imapSession{ctx: ctx, dialer: dialer, text: textproto.NewConn(conn)}
+	greeting, err := s.text.ReadLine()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("IMAP greeting failed: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		conn.Close()
+		return nil, fmt.Errorf("not an imap server or not accepting logins: %q", greeting)
+	}
+
+	capabilities, err := s.capabilities()
+	if err != nil {
+		conn.Close()
+		return nil, err
 	}
+	if slices.Contains(capabilities, "STARTTLS") {
+		status, message, _, err := s.command("STARTTLS")
+		if err == nil && status != "OK" {
+			err = fmt.Errorf("%s %s", status, message)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("IMAP STARTTLS failed: %w", err)
+		}
+		s.text = textproto.NewConn(tls.Client(conn, &tls.Config{
+			ServerName:         item.Target,
+			InsecureSkipVerify: true,
+		}))
+	}
+	return s, nil
+}
+
+func (s *imapSession) Accepts(item *brute.BruteItem) bool {
+	return true
+}
+
+func (s *imapSession) Authenticate(item *brute.BruteItem) (*brute.BruteResult, bool) {
+	result := brute.NewResult(item)
 	if item.Username == "" {
-		return result
+		return result.Unsupported("IMAP requires a username"), true
+	}
+	if strings.ContainsAny(item.Username+item.Password, "\r\n") {
+		return result.Unsupported("IMAP LOGIN cannot carry line breaks"), true
 	}
+	s.dialer.extend(item.Timeout)
 
-	ok, err := bruteutils.IMAPAuth(fmt.Sprintf("%s:%d", item.Target, item.Port), item.Username, item.Password)
+	status, message, bye, err := s.command("LOGIN " + imapQuote(item.Username) + " " + imapQuote(item.Password))
 	if err != nil {
-		result.Error = err
-		return result
+		return failWithContext(s.ctx, result, brute.ClassifyError(err), fmt.Errorf("IMAP login failed: %w", err)), false
 	}
+	switch status {
+	case "OK":
+		return result.Succeed("IMAP login successful"), false
+	case "NO":
+		result = failWithContext(s.ctx, result, classifyMailError(errors.New(message)), fmt.Errorf("IMAP login rejected: %s", message))
+		return result, !bye && result.FailureKind == brute.FailureAuthRejected
+	}
+	return result.Fail(brute.FailureProtocolError, fmt.Errorf("IMAP login failed: %s %s", status, message)), false
+}
+
+func (s *imapSession) Close() error {
+	s.dialer.extend(time.Second)
+	s.command("LOGOUT")
+	return s.text.Close()
+}
 
-	result.Success = ok
-	return result
+// capabilities 返回服务端声明的能力
+func (s *imapSession) capabilities() ([]string, error) {
+	tag := s.nextTag()
+	if err := s.text.PrintfLine("%s CAPABILITY", tag); err != nil {
+		return nil, fmt.Errorf("IMAP CAPABILITY failed: %w", err)
+	}
+	var capabilities []string
+	for {
+		line, err := s.text.ReadLine()
+		if err != nil {
+			return nil, fmt.Errorf("IMAP CAPABILITY failed: %w", err)
+		}
+		if fields, ok := strings.CutPrefix(line, "* CAPABILITY "); ok {
+			capabilities = append(capabilities, strings.Fields(strings.ToUpper(fields))...)
+		}
+		if strings.HasPrefix(line, tag+" ") {
+			return capabilities, nil
+		}
+	}
+}
+
+// command 发送带标签的命令并读取到同一标签的响应为止，返回响应状态和信息，bye 表示服务端已发送 BYE 准备断开
+func (s *imapSession) command(command string) (status, message string, bye bool, err error) {
+	tag := s.nextTag()
+	if err := s.text.PrintfLine("%s %s", tag, command); err != nil {
+		return "", "", false, err
+	}
+	for {
+		line, err := s.text.ReadLine()
+		if err != nil {
+			return "", "", bye, err
+		}
+		if strings.HasPrefix(line, "* BYE") {
+			bye = true
+		}
+		if rest, ok := strings.CutPrefix(line, tag+" "); ok {
+			status, message, _ = strings.Cut(rest, " ")
+			return strings.ToUpper(status), message, bye, nil
+		}
+	}
+}
+
+// nextTag 返回下一个命令标签
+func (s *imapSession) nextTag() string {
+	s.tag++
+	return fmt.Sprintf("a%d", s.tag)
+}
+
+// imapQuote 将字符串转为 IMAP 的引号字符串，转义反斜杠和双引号
+func imapQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// classifyMailError 推断 IMAP/POP3 错误的失败类型
+// bruteutils 不保留底层的连接错误，只能根据错误信息区分连接失败和认证失败
+func classifyMailError(err error) brute.FailureKind {
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "dial error"):
+		return brute.FailureConnRefused
+	case strings.Contains(message, "not an imap"),
+		strings.Contains(message, "unknown response"),
+		strings.Contains(message, "unexpected response"):
+		return brute.FailureProtocolError
+	}
+	return brute.ClassifyAuthError(err)
 }
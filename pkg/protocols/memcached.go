@@ -0,0 +1,105 @@
+package protocols
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
+)
+
+// memcached 二进制协议的常量
+const (
+	memcachedRequestMagic  = 0x80
+	memcachedResponseMagic = 0x81
+	memcachedOpSASLAuth    = 0x21
+	memcachedHeaderSize    = 24
+
+	memcachedStatusOK           = 0x0000
+	memcachedStatusAuthError    = 0x0020
+	memcachedStatusUnknownCmd   = 0x0081
+	memcachedStatusNotSupported = 0x0083
+)
+
+// MemcachedBrute Memcached SASL 爆破，使用二进制协议的 PLAIN 机制
+func MemcachedBrute(item *brute.BruteItem) *brute.BruteResult {
+	result := brute.NewResult(item)
+	if item.Username == "" {
+		return result.Unsupported("Memcached SASL requires a username")
+	}
+
+	ctx, cancel := item.TimeoutContext()
+	defer cancel()
+
+	address := fmt.Sprintf("%s:%d", item.Target, item.Port)
+	conn, err := utils.DialContext(ctx, "tcp", address, item.Timeout)
+	if err != nil {
+		return result.FailWithError(fmt.Errorf("Memcached dial failed: %w", err))
+	}
+	defer conn.Close()
+
+	mechanism := "PLAIN"
+	value := "\x00" + item.Username + "\x00" + item.Password
+	request := make([]byte, memcachedHeaderSize, memcachedHeaderSize+len(mechanism)+len(value))
+	request[0] = memcachedRequestMagic
+	request[1] = memcachedOpSASLAuth
+	binary.BigEndian.PutUint16(request[2:4], uint16(len(mechanism)))
+	binary.BigEndian.PutUint32(request[8:12], uint32(len(mechanism)+len(value)))
+	request = append(request, mechanism...)
+	request = append(request, value...)
+	if _, err := conn.Write(request); err != nil {
+		return result.FailWithError(fmt.Errorf("Memcached write failed: %w", err))
+	}
+
+	header := make([]byte, memcachedHeaderSize)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return result.FailWithError(fmt.Errorf("Memcached read failed: %w", err))
+	}
+	if header[0] != memcachedResponseMagic {
+		return result.Fail(brute.FailureProtocolError, errors.New("not a memcached binary protocol response"))
+	}
+
+	switch status := binary.BigEndian.Uint16(header[6:8]); status {
+	case memcachedStatusOK:
+		return result.Succeed("Memcached SASL authentication successful")
+	case memcachedStatusAuthError:
+		return result.AuthRejected(errors.New("Memcached SASL authentication failed"))
+	case memcachedStatusUnknownCmd, memcachedStatusNotSupported:
+		return result.Unsupported("Memcached server does not support SASL authentication")
+	default:
+		return result.Fail(brute.FailureProtocolError, fmt.Errorf("unexpected memcached status: 0x%04x", status))
+	}
+}
+
+// MemcachedUnauth 检查 Memcached 是否未启用 SASL，未启用时文本协议的 stats 命令直接返回统计信息
+func MemcachedUnauth(item *brute.BruteItem) *brute.BruteResult {
+	result := brute.NewResult(item)
+	ctx, cancel := item.TimeoutContext()
+	defer cancel()
+
+	address := fmt.Sprintf("%s:%d", item.Target, item.Port)
+	conn, err := utils.DialContext(ctx, "tcp", address, item.Timeout)
+	if err != nil {
+		return result.FailWithError(fmt.Errorf("Memcached dial failed: %w", err))
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("stats\r\n")); err != nil {
+		return result.FailWithError(fmt.Errorf("Memcached write failed: %w", err))
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		// 启用 SASL 的服务端不接受文本协议，直接关闭连接
+		return failWithContext(ctx, result, brute.FailureAuthRejected, fmt.Errorf("Memcached stats failed: %w", err))
+	}
+	if !bytes.HasPrefix(line, []byte("STAT ")) {
+		return result.AuthRejected(fmt.Errorf("Memcached stats rejected: %s", strings.TrimSpace(string(line))))
+	}
+
+	return result.Succeed("Memcached allows access without authentication")
+}
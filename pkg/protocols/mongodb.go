@@ -2,9 +2,14 @@ package protocols
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
+	"github.com/projectdiscovery/gologger"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -12,41 +17,105 @@ import (
 
 // MongoDBBrute MongoDB爆破
 func MongoDBBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
+	result := brute.NewResult(item)
 
 	timeout := item.Timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := item.TimeoutContext()
 	defer cancel()
 
-	// MongoDB连接URI
-	var dataSourceName string
-	if item.Username == "" && item.Password == "" {
-		dataSourceName = fmt.Sprintf("mongodb://%v:%v", item.Target, item.Port)
-	} else if item.Password != "" && item.Username != "" {
-		dataSourceName = fmt.Sprintf("mongodb://%s:%s@%v:%v/?authMechanism=SCRAM-SHA-1", item.Username, item.Password, item.Target, item.Port)
-	} else {
-		return result
+	// MongoDB连接URI，无需认证的访问由 MongoDBUnauth 检查
+	if item.Username == "" || item.Password == "" {
+		return result.Unsupported("MongoDB requires both username and password")
 	}
+	dataSourceName := fmt.Sprintf("mongodb://%s:%s@%v:%v/?authMechanism=SCRAM-SHA-1", item.Username, item.Password, item.Target, item.Port)
 
-	clientOptions := options.Client().ApplyURI(dataSourceName)
+	// 驱动的监控连接同样绑定到任务项的上下文，断开时不会遗留协程
+	clientOptions := options.Client().ApplyURI(dataSourceName).
+		SetDialer(utils.NewDialer(ctx, timeout)).
+		SetConnectTimeout(timeout).
+		SetServerSelectionTimeout(timeout)
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		result.Error = err
-		return result
+		return result.FailWithError(err)
 	}
 	defer client.Disconnect(ctx)
 
 	// 尝试ping数据库来验证连接
 	err = client.Ping(ctx, readpref.Primary())
 	if err != nil {
-		result.Error = err
-		return result
+		return result.Fail(classifyMongoError(err), err)
 	}
 
-	result.Success = true
-	result.Banner = "MongoDB connection successful"
+	result.Succeed("MongoDB connection successful")
+	if item.Enrich {
+		enrichMongoDB(ctx, client, result)
+	}
 	return result
 }
+
+// enrichMongoDB 采集 buildInfo 中的版本和可以列出的数据库
+func enrichMongoDB(ctx context.Context, client *mongo.Client, result *brute.BruteResult) {
+	var buildInfo struct {
+		Version string `bson:"version"`
+	}
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&buildInfo)
+	if err != nil {
+		gologger.Debug().Msgf("MongoDB enrichment failed for %s: %v", result.Item.Target, err)
+		return
+	}
+	result.SetExtra("version", buildInfo.Version)
+
+	// 没有 listDatabases 权限时只返回用户有权访问的数据库
+	databases, err := client.ListDatabaseNames(ctx, bson.D{}, options.ListDatabases().SetAuthorizedDatabases(true))
+	if err != nil {
+		gologger.Debug().Msgf("MongoDB enrichment failed for %s: %v", result.Item.Target, err)
+		return
+	}
+	result.SetExtra("databases", databases)
+}
+
+// MongoDBUnauth 检查 MongoDB 是否未启用认证，ping 不需要认证，以列出数据库确认
+func MongoDBUnauth(item *brute.BruteItem) *brute.BruteResult {
+	result := brute.NewResult(item)
+
+	timeout := item.Timeout
+	ctx, cancel := item.TimeoutContext()
+	defer cancel()
+
+	clientOptions := options.Client().ApplyURI(fmt.Sprintf("mongodb://%v:%v", item.Target, item.Port)).
+		SetDialer(utils.NewDialer(ctx, timeout)).
+		SetConnectTimeout(timeout).
+		SetServerSelectionTimeout(timeout)
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		return result.FailWithError(err)
+	}
+	defer client.Disconnect(ctx)
+
+	databases, err := client.ListDatabaseNames(ctx, bson.D{})
+	if err != nil {
+		return result.Fail(classifyMongoError(err), err)
+	}
+	result.Succeed("MongoDB allows access without authentication")
+	if item.Enrich {
+		result.SetExtra("databases", databases)
+	}
+	return result
+}
+
+// classifyMongoError 区分 MongoDB 认证失败和服务器选择失败
+func classifyMongoError(err error) brute.FailureKind {
+	var serverErr mongo.ServerError
+	switch {
+	case mongo.IsTimeout(err):
+		return brute.FailureTimeout
+	case strings.Contains(err.Error(), "auth error"), strings.Contains(err.Error(), "AuthenticationFailed"):
+		return brute.FailureAuthRejected
+	case errors.As(err, &serverErr) && serverErr.HasErrorCode(mongoUnauthorizedCode):
+		return brute.FailureAuthRejected
+	}
+	return brute.ClassifyError(err)
+}
+
+// mongoUnauthorizedCode 未授权时 MongoDB 返回的错误码
+const mongoUnauthorizedCode = 13
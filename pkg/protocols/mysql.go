@@ -3,24 +3,23 @@ package protocols
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	"github.com/projectdiscovery/gologger"
 )
 
 // MySQLBrute MySQL爆破
 func MySQLBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
+	result := brute.NewResult(item)
 	if item.Username == "" {
-		return result
+		return result.Unsupported("MySQL requires a username")
 	}
 
-	// 创建带超时的上下文
-	ctx, cancel := context.WithTimeout(context.Background(), item.Timeout)
+	// 在任务项的上下文中连接，引擎停止时驱动会关闭正在使用的连接
+	ctx, cancel := item.TimeoutContext()
 	defer cancel()
 
 	// 构建DSN连接字符串，添加更多参数
@@ -30,8 +29,7 @@ func MySQLBrute(item *brute.BruteItem) *brute.BruteResult {
 
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
-		result.Error = fmt.Errorf("failed to create MySQL connection: %w", err)
-		return result
+		return result.Fail(brute.FailureUnknown, fmt.Errorf("failed to create MySQL connection: %w", err))
 	}
 
 	// 确保连接关闭
@@ -48,7 +46,16 @@ func MySQLBrute(item *brute.BruteItem) *brute.BruteResult {
 
 	// 使用带上下文的Ping验证连接
 	if err := db.PingContext(ctx); err != nil {
-		result.Error = fmt.Errorf("failed to connect to MySQL: %w", err)
+		result.Fail(classifyMySQLError(err), fmt.Errorf("failed to connect to MySQL: %w", err))
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) {
+			switch mysqlErr.Number {
+			case 3118: // 账号被锁定，该用户的其他密码无需再试
+				result.UserEliminated = true
+			case 1129, 1130: // 来源地址被封禁，任何凭据都无法登录
+				result.Finished = true
+			}
+		}
 		return result
 	}
 
@@ -56,11 +63,57 @@ func MySQLBrute(item *brute.BruteItem) *brute.BruteResult {
 	var version string
 	err = db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version)
 	if err != nil {
-		result.Error = fmt.Errorf("failed to query MySQL: %w", err)
-		return result
+		return result.Fail(classifyMySQLError(err), fmt.Errorf("failed to query MySQL: %w", err))
 	}
 
-	result.Success = true
-	result.Banner = fmt.Sprintf("MySQL connection successful - %s", version)
+	result.Succeed(fmt.Sprintf("MySQL connection successful - %s", version))
+	if item.Enrich {
+		result.SetExtra("version", version)
+		enrichMySQL(ctx, db, result)
+	}
 	return result
 }
+
+// enrichMySQL 采集当前用户及其权限
+func enrichMySQL(ctx context.Context, db *sql.DB, result *brute.BruteResult) {
+	var user string
+	if err := db.QueryRowContext(ctx, "SELECT CURRENT_USER()").Scan(&user); err != nil {
+		gologger.Debug().Msgf("MySQL enrichment failed for %s: %v", result.Item.Target, err)
+		return
+	}
+	result.SetExtra("current_user", user)
+
+	rows, err := db.QueryContext(ctx, "SHOW GRANTS")
+	if err != nil {
+		gologger.Debug().Msgf("MySQL enrichment failed for %s: %v", result.Item.Target, err)
+		return
+	}
+	defer rows.Close()
+	var grants []string
+	for rows.Next() {
+		var grant string
+		if err := rows.Scan(&grant); err != nil {
+			break
+		}
+		grants = append(grants, grant)
+	}
+	result.SetExtra("grants", grants)
+}
+
+// classifyMySQLError 根据 MySQL 错误码区分认证失败和封禁
+func classifyMySQLError(err error) brute.FailureKind {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1045, 1044: // ER_ACCESS_DENIED_ERROR, ER_DBACCESS_DENIED_ERROR
+			return brute.FailureAuthRejected
+		case 1129, 1130, 3118: // ER_HOST_IS_BLOCKED, ER_HOST_NOT_PRIVILEGED, ER_ACCOUNT_HAS_BEEN_LOCKED
+			return brute.FailureLocked
+		}
+		return brute.FailureProtocolError
+	}
+	if errors.Is(err, mysql.ErrMalformPkt) {
+		return brute.FailureProtocolError
+	}
+	return brute.ClassifyError(err)
+}
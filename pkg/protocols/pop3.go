@@ -2,31 +2,111 @@ package protocols
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net/smtp"
+	"strings"
+	"time"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
 	"github.com/yaklang/yaklang/common/utils/bruteutils"
+	"github.com/yaklang/yaklang/common/utils/pop3"
 )
 
-// POP3Brute POP3爆破
+// POP3Brute POP3爆破，连接在任务项的上下文中建立，超时或取消时立即关闭
 func POP3Brute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
 	if item.Username == "" {
-		return result
+		return brute.NewResult(item).Unsupported("POP3 requires a username")
 	}
+	return authenticateOnce(item, newPOP3Session)
+}
 
-	timeout := item.Timeout
-	_, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+// pop3Session POP3 会话，认证失败后服务端仍处于 AUTHORIZATION 状态，可以继续尝试
+type pop3Session struct {
+	ctx    context.Context
+	dialer *sessionDialer
+	conn   *pop3.Conn
+	caps   map[string]string
+}
 
-	ok, err := bruteutils.POP3Auth(fmt.Sprintf("%s:%d", item.Target, item.Port), item.Username, item.Password, true)
+// newPOP3Session 与 bruteutils.POP3Auth 相同的流程，连接改为绑定到上下文
+func newPOP3Session(ctx context.Context, item *brute.BruteItem) (brute.Session, error) {
+	dialer := newSessionDialer(ctx, item.Timeout)
+	p := pop3.New(pop3.Opt{
+		Host:   item.Target,
+		Port:   item.Port,
+		Dialer: dialer,
+	})
+	c, err := p.NewConn()
 	if err != nil {
-		result.Error = err
-		return result
+		return nil, fmt.Errorf("POP3 dial failed: %w", err)
+	}
+	dialer.extend(item.Timeout)
+
+	caps, _ := c.CAPA()
+	if _, ok := caps["STLS"]; ok {
+		if err := c.StartTLS(&tls.Config{
+			ServerName:         item.Target,
+			MinVersion:         tls.VersionTLS10,
+			InsecureSkipVerify: true,
+			Renegotiation:      tls.RenegotiateFreelyAsClient,
+		}); err != nil {
+			c.Quit()
+			return nil, fmt.Errorf("POP3 STLS failed: %w", err)
+		}
 	}
-	result.Success = ok
-	return result
+	return &pop3Session{ctx: ctx, dialer: dialer, conn: c, caps: caps}, nil
+}
+
+func (s *pop3Session) Accepts(item *brute.BruteItem) bool {
+	return true
+}
+
+func (s *pop3Session) Authenticate(item *brute.BruteItem) (*brute.BruteResult, bool) {
+	result := brute.NewResult(item)
+	if item.Username == "" {
+		return result.Unsupported("POP3 requires a username"), true
+	}
+	s.dialer.extend(item.Timeout)
+
+	// 服务端声明 SASL 时优先使用 SASL 认证，否则使用 USER/PASS 命令
+	if ext, ok := s.caps["SASL"]; ok {
+		var auth smtp.Auth
+		switch {
+		case strings.Contains(ext, "PLAIN"):
+			auth = bruteutils.PlainAuth("", item.Username, item.Password, item.Target)
+		case strings.Contains(ext, "LOGIN"):
+			auth = bruteutils.LoginAuth(item.Username, item.Password)
+		case strings.Contains(ext, "CRAM-MD5"):
+			auth = smtp.CRAMMD5Auth(item.Username, item.Password)
+		case strings.Contains(ext, "SCRAM"):
+			var err error
+			if auth, err = bruteutils.ScramAuth(ext, item.Username, item.Password); err != nil {
+				return result.Fail(brute.FailureUnsupported, err), false
+			}
+		}
+		if auth != nil {
+			if err := s.conn.SASLAuth(auth); err != nil {
+				return s.fail(result, err)
+			}
+		}
+	} else if err := s.conn.Auth(item.Username, item.Password); err != nil {
+		return s.fail(result, err)
+	}
+
+	if _, _, err := s.conn.Stat(); err != nil {
+		return s.fail(result, err)
+	}
+	return result.Succeed("POP3 login successful"), false
+}
+
+// fail 记录认证失败，只有明确被拒绝时会话才能继续使用
+func (s *pop3Session) fail(result *brute.BruteResult, err error) (*brute.BruteResult, bool) {
+	result = failWithContext(s.ctx, result, classifyMailError(err), err)
+	return result, result.FailureKind == brute.FailureAuthRejected
+}
+
+func (s *pop3Session) Close() error {
+	s.dialer.extend(time.Second)
+	return s.conn.Quit()
 }
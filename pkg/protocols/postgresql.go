@@ -1,30 +1,42 @@
 package protocols
 
 import (
+	"errors"
+	"net"
+	"strconv"
 	"strings"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
 	"github.com/go-pg/pg/v10"
 )
 
 // PostgreSQLBrute PostgreSQL爆破
 func PostgreSQLBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
+	result := brute.NewResult(item)
 	if item.Username == "" {
-		return result
+		return result.Unsupported("PostgreSQL requires a username")
 	}
 
+	ctx, cancel := item.TimeoutContext()
+	defer cancel()
+
 	db := pg.Connect(&pg.Options{
-		Addr:     item.Target,
-		User:     item.Username,
-		Password: item.Password,
-		Database: "postgres",
+		Addr:         net.JoinHostPort(item.Target, strconv.Itoa(item.Port)),
+		Dialer:       utils.NewDialer(ctx, item.Timeout).DialContext,
+		User:         item.Username,
+		Password:     item.Password,
+		Database:     "postgres",
+		DialTimeout:  item.Timeout,
+		ReadTimeout:  item.Timeout,
+		WriteTimeout: item.Timeout,
+		MaxRetries:   0,
 	})
-	_, err := db.Exec("select 1")
+	defer db.Close()
+
+	_, err := db.WithContext(ctx).Exec("select 1")
 	if err != nil {
+		result.Fail(classifyPostgreSQLError(err), err)
 		switch true {
 		case strings.Contains(err.Error(), "connect: connection refused"):
 			fallthrough
@@ -38,6 +50,20 @@ func PostgreSQLBrute(item *brute.BruteItem) *brute.BruteResult {
 		}
 		return result
 	}
-	result.Success = true
-	return result
+	return result.Succeed("PostgreSQL connection successful")
+}
+
+// classifyPostgreSQLError 根据 SQLSTATE 区分认证失败和访问控制
+func classifyPostgreSQLError(err error) brute.FailureKind {
+	var pgErr pg.Error
+	if errors.As(err, &pgErr) {
+		switch pgErr.Field('C') {
+		case "28P01": // invalid_password
+			return brute.FailureAuthRejected
+		case "28000": // invalid_authorization_specification，例如 pg_hba.conf 拒绝来源地址
+			return brute.FailureLocked
+		}
+		return brute.FailureProtocolError
+	}
+	return brute.ClassifyError(err)
 }
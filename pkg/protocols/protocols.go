@@ -1,6 +1,27 @@
 package protocols
 
+import (
+	"context"
+	"fmt"
+
+	"github.com/XTeam-Wing/x-crack/pkg/brute"
+)
+
 // init 函数注册所有协议处理器
 func init() {
 	RegisterAllProtocols()
 }
+
+// failWithContext 记录失败，上下文结束导致的失败归类为超时或取消
+// 部分协议库只保留错误信息而丢弃底层错误，无法从错误本身区分连接被关闭的原因
+func failWithContext(ctx context.Context, result *brute.BruteResult, kind brute.FailureKind, err error) *brute.BruteResult {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ctxErr, err)
+		} else {
+			err = ctxErr
+		}
+		return result.Fail(brute.ClassifyError(ctxErr), err)
+	}
+	return result.Fail(kind, err)
+}
@@ -1,10 +1,19 @@
 package protocols
 
 import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestAMQPBrute(t *testing.T) {
@@ -42,3 +51,243 @@ func TestMongoDBBrute(t *testing.T) {
 	}
 	t.Logf("MongoDBBrute result: %+v", result)
 }
+
+// sessionTestUser 会话测试中服务端接受的凭据
+const (
+	sessionTestUser     = "admin"
+	sessionTestPassword = "secret"
+)
+
+// listenSessionTest 监听本地端口并用 serve 处理每个连接，返回端口和已建立的连接数
+func listenSessionTest(t *testing.T, serve func(conn net.Conn)) (int, *int64) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var accepted int64
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt64(&accepted, 1)
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, &accepted
+}
+
+// serveLines 逐行读取命令并写回 reply 返回的响应，reply 返回 false 时关闭连接
+func serveLines(greeting string, reply func(command, argument string) (string, bool)) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		text := textproto.NewConn(conn)
+		text.PrintfLine("%s", greeting)
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			command, argument, _ := strings.Cut(line, " ")
+			response, ok := reply(command, argument)
+			text.PrintfLine("%s", response)
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// fakeFTPServer 只接受 sessionTestUser 的 FTP 服务端
+func fakeFTPServer() func(conn net.Conn) {
+	return func(conn net.Conn) {
+		var user string
+		serveLines("220 fake FTP ready", func(command, argument string) (string, bool) {
+			switch command {
+			case "USER":
+				user = argument
+				return "331 password required", true
+			case "PASS":
+				if user == sessionTestUser && argument == sessionTestPassword {
+					return "230 logged in", true
+				}
+				return "530 login incorrect", true
+			case "TYPE":
+				return "200 type set", true
+			case "PWD":
+				return `257 "/" is the current directory`, true
+			case "QUIT":
+				return "221 bye", false
+			}
+			return "502 command not implemented", true
+		})(conn)
+	}
+}
+
+// fakePOP3Server 只接受 sessionTestUser 的 POP3 服务端，使用 USER/PASS 认证
+func fakePOP3Server() func(conn net.Conn) {
+	return func(conn net.Conn) {
+		var user string
+		authorized := false
+		serveLines("+OK fake POP3 ready", func(command, argument string) (string, bool) {
+			switch command {
+			case "CAPA":
+				return "+OK\r\nUSER\r\n.", true
+			case "USER":
+				user = argument
+				return "+OK", true
+			case "PASS":
+				if user == sessionTestUser && argument == sessionTestPassword {
+					authorized = true
+					return "+OK logged in", true
+				}
+				return "-ERR [AUTH] invalid password", true
+			case "NOOP":
+				return "+OK", true
+			case "STAT":
+				if authorized {
+					return "+OK 0 0", true
+				}
+			case "QUIT":
+				return "+OK bye", false
+			}
+			return "-ERR unknown command", true
+		})(conn)
+	}
+}
+
+// fakeSMTPServer 只接受 sessionTestUser 的 SMTP 服务端，使用 AUTH PLAIN 认证
+func fakeSMTPServer() func(conn net.Conn) {
+	return serveLines("220 fake ESMTP ready", func(command, argument string) (string, bool) {
+		switch command {
+		case "EHLO":
+			return "250-fake\r\n250 AUTH PLAIN", true
+		case "AUTH":
+			mechanism, response, _ := strings.Cut(argument, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(response)
+			if mechanism == "PLAIN" && string(decoded) == "\x00"+sessionTestUser+"\x00"+sessionTestPassword {
+				return "235 2.7.0 authentication successful", true
+			}
+			return "535 5.7.8 authentication credentials invalid", true
+		case "QUIT":
+			return "221 bye", false
+		}
+		return "502 command not implemented", true
+	})
+}
+
+// fakeIMAPServer 只接受 sessionTestUser 的 IMAP 服务端，使用带标签的 LOGIN 认证
+func fakeIMAPServer() func(conn net.Conn) {
+	return serveLines("* OK fake IMAP ready", func(tag, argument string) (string, bool) {
+		command, argument, _ := strings.Cut(argument, " ")
+		switch strings.ToUpper(command) {
+		case "CAPABILITY":
+			return "* CAPABILITY IMAP4rev1 AUTH=PLAIN\r\n" + tag + " OK CAPABILITY completed", true
+		case "LOGIN":
+			if argument == `"`+sessionTestUser+`" "`+sessionTestPassword+`"` {
+				return tag + " OK LOGIN completed", true
+			}
+			return tag + " NO [AUTHENTICATIONFAILED] invalid credentials", true
+		case "LOGOUT":
+			return "* BYE logging out\r\n" + tag + " OK LOGOUT completed", false
+		}
+		return tag + " BAD unknown command", true
+	})
+}
+
+// fakeSSHServer 只接受 sessionTestUser 的 SSH 服务端，认证后接受会话通道
+func fakeSSHServer(t *testing.T) func(conn net.Conn) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate host key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("Failed to create host key signer: %v", err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if meta.User() == sessionTestUser && string(password) == sessionTestPassword {
+				return nil, nil
+			}
+			return nil, errors.New("password rejected")
+		},
+	}
+	config.AddHostKey(signer)
+
+	return func(conn net.Conn) {
+		_, chans, reqs, err := ssh.NewServerConn(conn, config)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		for channel := range chans {
+			accepted, requests, err := channel.Accept()
+			if err != nil {
+				continue
+			}
+			go ssh.DiscardRequests(requests)
+			accepted.Close()
+		}
+	}
+}
+
+func TestSessionsReuseConnection(t *testing.T) {
+	tests := []struct {
+		name    string
+		serve   func(conn net.Conn)
+		factory brute.SessionFactory
+	}{
+		{"ssh", fakeSSHServer(t), newSSHSession},
+		{"ftp", fakeFTPServer(), newFTPSession},
+		{"pop3", fakePOP3Server(), newPOP3Session},
+		{"smtp", fakeSMTPServer(), newSMTPSession},
+		{"imap", fakeIMAPServer(), newIMAPSession},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, accepted := listenSessionTest(t, tt.serve)
+			newItem := func(password string) *brute.BruteItem {
+				return &brute.BruteItem{
+					Type:     tt.name,
+					Target:   "127.0.0.1",
+					Port:     port,
+					Username: sessionTestUser,
+					Password: password,
+					Timeout:  5 * time.Second,
+				}
+			}
+
+			session, err := tt.factory(context.Background(), newItem("wrong1"))
+			if err != nil {
+				t.Fatalf("Failed to open session: %v", err)
+			}
+			defer session.Close()
+
+			// 被拒绝的凭据不影响会话，之后的凭据在同一连接上继续尝试
+			for _, password := range []string{"wrong1", "wrong2"} {
+				result, reusable := session.Authenticate(newItem(password))
+				if result.Success || result.FailureKind != brute.FailureAuthRejected {
+					t.Fatalf("Expected %s to be rejected, got success=%v kind=%s error=%v",
+						password, result.Success, result.FailureKind, result.Error)
+				}
+				if !reusable {
+					t.Fatalf("Expected the session to stay reusable after rejecting %s", password)
+				}
+			}
+			result, _ := session.Authenticate(newItem(sessionTestPassword))
+			if !result.Success {
+				t.Fatalf("Expected the valid credential to succeed, got kind=%s error=%v", result.FailureKind, result.Error)
+			}
+			if n := atomic.LoadInt64(accepted); n != 1 {
+				t.Fatalf("Expected all attempts over 1 connection, got %d", n)
+			}
+		})
+	}
+}
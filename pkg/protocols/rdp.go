@@ -2,33 +2,32 @@ package protocols
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
 	"github.com/XTeam-Wing/x-crack/pkg/protocols/grdp"
+	"github.com/projectdiscovery/gologger"
 )
 
 // RDPBrute RDP爆破
 func RDPBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
+	result := brute.NewResult(item)
 	if item.Username == "" {
-		return result
+		return result.Unsupported("RDP requires a username")
 	}
 
 	target := fmt.Sprintf("%s:%d", item.Target, item.Port)
 
-	ctx, cancel := context.WithTimeout(context.Background(), item.Timeout)
+	// grdp 的连接绑定到任务项的上下文，超时或取消时连接关闭，登录协程随之退出
+	ctx, cancel := item.TimeoutContext()
 	defer cancel()
 
-	var err error
-
-	// 检查协议类型并尝试登录，使用 goroutine + select 模式处理超时
+	// 检查协议类型并尝试登录，grdp 基于事件回调，使用 goroutine + select 模式处理超时
 	protocolChan := make(chan string, 1)
 	go func() {
-		protocolChan <- grdp.VerifyProtocol(target)
+		protocolChan <- grdp.VerifyProtocolContext(ctx, target)
 	}()
 
 	var protocol string
@@ -37,47 +36,41 @@ func RDPBrute(item *brute.BruteItem) *brute.BruteResult {
 		// 正常获取到协议类型
 	case <-ctx.Done():
 		// 超时或取消
-		result.Error = fmt.Errorf("RDP protocol verification timeout: %w", ctx.Err())
-		return result
+		return result.FailWithError(fmt.Errorf("RDP protocol verification timeout: %w", ctx.Err()))
 	}
 
-	fmt.Println("Detected protocol:", protocol)
+	gologger.Debug().Msgf("Detected RDP protocol for %s: %s", target, protocol)
+	login := grdp.LoginForRDPContext
 	if protocol == grdp.PROTOCOL_SSL {
-		// 需要检查grdp库是否支持上下文，如果不支持，使用goroutine+select模式
-		errChan := make(chan error, 1)
-		go func() {
-			errChan <- grdp.LoginForSSL(target, item.Target, item.Username, item.Password)
-		}()
+		login = grdp.LoginForSSLContext
+	}
 
-		select {
-		case err = <-errChan:
-			// 正常完成
-		case <-ctx.Done():
-			// 超时或取消
-			err = ctx.Err()
-		}
-	} else {
-		// 同样的模式处理RDP
-		errChan := make(chan error, 1)
-		go func() {
-			errChan <- grdp.LoginForRDP(target, item.Target, item.Username, item.Password)
-		}()
+	var err error
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- login(ctx, target, item.Target, item.Username, item.Password)
+	}()
 
-		select {
-		case err = <-errChan:
-			// 正常完成
-		case <-ctx.Done():
-			// 超时或取消
-			err = ctx.Err()
-		}
+	select {
+	case err = <-errChan:
+		// 正常完成
+	case <-ctx.Done():
+		// 超时或取消
+		err = ctx.Err()
 	}
 
 	if err != nil {
-		result.Error = fmt.Errorf("RDP connection failed: %w", err)
-		return result
+		return failWithContext(ctx, result, classifyRDPError(err), fmt.Errorf("RDP connection failed: %w", err))
 	}
 
-	result.Success = true
-	result.Banner = "RDP connection successful"
-	return result
+	return result.Succeed("RDP connection successful")
+}
+
+// classifyRDPError 区分 RDP 连接失败和认证失败
+// grdp 以字符串形式返回错误，连接阶段的错误带有 "[dial err]" 前缀
+func classifyRDPError(err error) brute.FailureKind {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) || strings.HasPrefix(err.Error(), "[dial err]") {
+		return brute.ClassifyError(err)
+	}
+	return brute.ClassifyAuthError(err)
 }
@@ -1,48 +1,92 @@
 package protocols
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
 	"github.com/go-redis/redis/v8"
+	"github.com/projectdiscovery/gologger"
 )
 
 // RedisBrute Redis爆破
 func RedisBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
+	result := brute.NewResult(item)
 	if item.Username != "" {
 		// Redis一般不使用用户名认证
-		return result
+		return result.Unsupported("Redis authenticates with password only")
 	}
 
 	timeout := item.Timeout
+	ctx, cancel := item.TimeoutContext()
+	defer cancel()
 
-	// Redis连接配置
+	// Redis连接配置，连接绑定到任务项的上下文
 	rdb := redis.NewClient(&redis.Options{
 		Addr:        fmt.Sprintf("%s:%d", item.Target, item.Port),
 		Password:    item.Password,
 		DB:          0,
+		Dialer:      utils.NewDialer(ctx, timeout).DialContext,
 		DialTimeout: timeout,
 		ReadTimeout: timeout / 2,
 		MaxRetries:  1,
 	})
 	defer rdb.Close()
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
 	// 尝试连接并执行PING命令
 	_, err := rdb.Ping(ctx).Result()
 	if err != nil {
-		result.Error = err
-		return result
+		var redisErr redis.Error
+		if errors.As(err, &redisErr) {
+			// WRONGPASS、NOAUTH 等服务端错误
+			return result.AuthRejected(err)
+		}
+		return result.FailWithError(err)
 	}
 
-	result.Success = true
-	result.Banner = "Redis connection successful"
+	result.Succeed("Redis connection successful")
+	if item.Enrich {
+		enrichRedis(ctx, rdb, result)
+	}
 	return result
 }
+
+// enrichRedis 从 INFO 中采集服务端版本、操作系统和主从角色
+func enrichRedis(ctx context.Context, rdb *redis.Client, result *brute.BruteResult) {
+	for section, keys := range map[string][]string{
+		"server":      {"redis_version", "redis_mode", "os"},
+		"replication": {"role"},
+	} {
+		info, err := rdb.Info(ctx, section).Result()
+		if err != nil {
+			gologger.Debug().Msgf("Redis enrichment failed for %s: %v", result.Item.Target, err)
+			return
+		}
+		fields := parseRedisInfo(info)
+		for _, key := range keys {
+			if value, ok := fields[key]; ok {
+				result.SetExtra(key, value)
+			}
+		}
+	}
+}
+
+// parseRedisInfo 解析 INFO 返回的 key:value 行
+func parseRedisInfo(info string) map[string]string {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			fields[key] = value
+		}
+	}
+	return fields
+}
@@ -3,29 +3,40 @@ package protocols
 import (
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
 	"github.com/XTeam-Wing/x-crack/pkg/protocols/telnet"
+	"github.com/projectdiscovery/gologger"
 )
 
-// RegisterAllProtocols 注册所有协议处理器
-func RegisterAllProtocols() {
-	brute.RegisterProtocolHandler("socks5", SOCKS5Brute)
-	// 注册HTTP代理爆破处理器
-	brute.RegisterProtocolHandler("http_proxy", HTTPProxyBrute)
-	brute.RegisterProtocolHandler("ssh", SSHBrute)
-	brute.RegisterProtocolHandler("ftp", FTPBrute)
-	brute.RegisterProtocolHandler("telnet", telnet.TelnetBrute)
-	brute.RegisterProtocolHandler("mysql", MySQLBrute)
-	brute.RegisterProtocolHandler("postgresql", PostgreSQLBrute)
-	brute.RegisterProtocolHandler("redis", RedisBrute)
-	brute.RegisterProtocolHandler("mongodb", MongoDBBrute)
-	brute.RegisterProtocolHandler("http", HTTPBrute)
-	brute.RegisterProtocolHandler("https", HTTPSBrute)
-	brute.RegisterProtocolHandler("smb", SMBBrute)
-	brute.RegisterProtocolHandler("rdp", RDPBrute)
-	brute.RegisterProtocolHandler("vnc", VNCBrute)
-	brute.RegisterProtocolHandler("snmp", SNMPBrute)
-	brute.RegisterProtocolHandler("imap", IMAPBrute)
-	brute.RegisterProtocolHandler("pop3", POP3Brute)
-	brute.RegisterProtocolHandler("smtp", SMTPBrute)
-	brute.RegisterProtocolHandler("amqp", AMQPBrute)
+// builtinProtocols 内置协议的元数据和认证函数
+var builtinProtocols = []brute.ProtocolHandler{
+	brute.WithUnauthProbe(brute.NewProtocolHandler(brute.ProtocolInfo{Name: "socks5", DefaultPorts: []int{1080}}, SOCKS5Brute), SOCKS5Unauth),
+	// HTTP代理爆破处理器
+	brute.WithUnauthProbe(brute.NewProtocolHandler(brute.ProtocolInfo{Name: "http_proxy", DefaultPorts: []int{8080, 3128}}, HTTPProxyBrute), HTTPProxyUnauth),
+	brute.NewSessionProtocolHandler(brute.ProtocolInfo{Name: "ssh", DefaultPorts: []int{22}}, SSHBrute, newSSHSession),
+	brute.WithUnauthProbe(brute.NewSessionProtocolHandler(brute.ProtocolInfo{Name: "ftp", DefaultPorts: []int{21}}, FTPBrute, newFTPSession), FTPUnauth),
+	brute.NewProtocolHandler(brute.ProtocolInfo{Name: "telnet", DefaultPorts: []int{23}, AuthModel: brute.AuthUsernameOptional}, telnet.TelnetBrute),
+	brute.NewProtocolHandler(brute.ProtocolInfo{Name: "mysql", DefaultPorts: []int{3306}}, MySQLBrute),
+	brute.NewProtocolHandler(brute.ProtocolInfo{Name: "postgresql", Aliases: []string{"postgres", "pgsql"}, DefaultPorts: []int{5432}}, PostgreSQLBrute),
+	brute.WithUnauthProbe(brute.NewProtocolHandler(brute.ProtocolInfo{Name: "redis", DefaultPorts: []int{6379}, AuthModel: brute.AuthPasswordOnly}, RedisBrute), RedisUnauth),
+	brute.WithUnauthProbe(brute.NewProtocolHandler(brute.ProtocolInfo{Name: "mongodb", Aliases: []string{"mongo"}, DefaultPorts: []int{27017}}, MongoDBBrute), MongoDBUnauth),
+	brute.NewProtocolHandler(brute.ProtocolInfo{Name: "http", DefaultPorts: []int{80, 8080, 8000, 8888}}, HTTPBrute),
+	brute.NewProtocolHandler(brute.ProtocolInfo{Name: "https", DefaultPorts: []int{443, 8443}, TLS: true}, HTTPSBrute),
+	brute.NewProtocolHandler(brute.ProtocolInfo{Name: "smb", DefaultPorts: []int{445, 139}}, SMBBrute),
+	brute.NewProtocolHandler(brute.ProtocolInfo{Name: "rdp", DefaultPorts: []int{3389}}, RDPBrute),
+	brute.WithUnauthProbe(brute.NewProtocolHandler(brute.ProtocolInfo{Name: "vnc", DefaultPorts: []int{5900, 5901, 5902}, AuthModel: brute.AuthPasswordOnly}, VNCBrute), VNCUnauth),
+	brute.WithUnauthProbe(brute.NewProtocolHandler(brute.ProtocolInfo{Name: "snmp", DefaultPorts: []int{161}, Transport: brute.TransportUDP, AuthModel: brute.AuthPasswordOnly}, SNMPBrute), SNMPUnauth),
+	brute.NewSessionProtocolHandler(brute.ProtocolInfo{Name: "imap", DefaultPorts: []int{143, 993}}, IMAPBrute, newIMAPSession),
+	brute.NewSessionProtocolHandler(brute.ProtocolInfo{Name: "pop3", DefaultPorts: []int{110, 995}, TLS: true}, POP3Brute, newPOP3Session),
+	brute.NewSessionProtocolHandler(brute.ProtocolInfo{Name: "smtp", DefaultPorts: []int{25, 587, 465}, TLS: true}, SMTPBrute, newSMTPSession),
+	brute.NewProtocolHandler(brute.ProtocolInfo{Name: "amqp", DefaultPorts: []int{5672}}, AMQPBrute),
+	brute.WithUnauthProbe(brute.NewProtocolHandler(brute.ProtocolInfo{Name: "memcached", DefaultPorts: []int{11211}}, MemcachedBrute), MemcachedUnauth),
+	brute.WithUnauthProbe(brute.NewProtocolHandler(brute.ProtocolInfo{Name: "zookeeper", Aliases: []string{"zk"}, DefaultPorts: []int{2181}, AuthModel: brute.AuthNone}, ZooKeeperBrute), ZooKeeperUnauth),
+}
 
+// RegisterAllProtocols 注册所有内置协议处理器
+func RegisterAllProtocols() {
+	for _, handler := range builtinProtocols {
+		if err := brute.RegisterProtocol(handler); err != nil {
+			gologger.Fatal().Msgf("Failed to register protocol %s: %v", handler.Info().Name, err)
+		}
+	}
 }
@@ -0,0 +1,53 @@
+package protocols
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
+)
+
+// sessionDialer 记录会话的控制连接，每次尝试前为连接设置新的截止时间
+// 会话的连接绑定到目标的上下文，单次尝试的超时只能通过连接的截止时间限制
+type sessionDialer struct {
+	*utils.Dialer
+	conn net.Conn
+}
+
+// newSessionDialer 创建绑定上下文的会话拨号器
+func newSessionDialer(ctx context.Context, timeout time.Duration) *sessionDialer {
+	return &sessionDialer{Dialer: utils.NewDialer(ctx, timeout)}
+}
+
+// Dial 建立连接，第一个连接作为会话的控制连接
+func (d *sessionDialer) Dial(network, address string) (net.Conn, error) {
+	conn, err := d.Dialer.Dial(network, address)
+	if err == nil && d.conn == nil {
+		d.conn = conn
+	}
+	return conn, err
+}
+
+// extend 将控制连接的截止时间设置为 timeout 之后
+func (d *sessionDialer) extend(timeout time.Duration) {
+	if d.conn != nil && timeout > 0 {
+		d.conn.SetDeadline(time.Now().Add(timeout))
+	}
+}
+
+// authenticateOnce 在任务项的上下文中建立会话并只尝试一次，未启用会话复用时使用
+func authenticateOnce(item *brute.BruteItem, factory brute.SessionFactory) *brute.BruteResult {
+	ctx, cancel := item.TimeoutContext()
+	defer cancel()
+
+	session, err := factory(ctx, item)
+	if err != nil {
+		return brute.NewResult(item).FailWithError(err)
+	}
+	defer session.Close()
+
+	result, _ := session.Authenticate(item)
+	return result
+}
@@ -1,31 +1,40 @@
 package protocols
 
 import (
-	"fmt"
+	"errors"
 	"net"
+	"strconv"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
 	"github.com/hirochachacha/go-smb2"
 )
 
+// SMB 认证相关的 NTSTATUS
+const (
+	statusLogonFailure     = 0xC000006D
+	statusPasswordExpired  = 0xC0000071
+	statusAccountDisabled  = 0xC0000072
+	statusAccountLockedOut = 0xC0000234
+)
+
 // SMBBrute SMB爆破
 func SMBBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
+	result := brute.NewResult(item)
 	if item.Username == "" {
-		return result
+		return result.Unsupported("SMB requires a username")
 	}
 
 	timeout := item.Timeout
-	address := fmt.Sprintf("%s:%d", item.Target, item.Port)
+	address := net.JoinHostPort(item.Target, strconv.Itoa(item.Port))
+
+	ctx, cancel := item.TimeoutContext()
+	defer cancel()
 
 	// 连接到SMB服务器
-	conn, err := net.DialTimeout("tcp", address, timeout)
+	conn, err := utils.DialContext(ctx, "tcp", address, timeout)
 	if err != nil {
-		result.Error = err
-		return result
+		return result.FailWithError(err)
 	}
 	defer conn.Close()
 
@@ -37,22 +46,45 @@ func SMBBrute(item *brute.BruteItem) *brute.BruteResult {
 		},
 	}
 
-	s, err := d.Dial(conn)
+	s, err := d.DialContext(ctx, conn)
 	if err != nil {
-		result.Error = err
+		result.Fail(classifySMBError(err), err)
+		// 账号被锁定、禁用或密码过期时，该用户的其他密码无需再试
+		result.UserEliminated = result.FailureKind == brute.FailureLocked
 		return result
 	}
 	defer s.Logoff()
 
 	// 尝试连接到IPC$共享来验证认证
-	fs, err := s.Mount("IPC$")
+	fs, err := s.WithContext(ctx).Mount("IPC$")
 	if err != nil {
-		result.Error = err
-		return result
+		return result.Fail(classifySMBError(err), err)
 	}
 	defer fs.Umount()
 
-	result.Success = true
-	result.Banner = "SMB authentication successful"
-	return result
+	return result.Succeed("SMB authentication successful")
+}
+
+// classifySMBError 根据 NTSTATUS 区分认证失败和账号锁定
+func classifySMBError(err error) brute.FailureKind {
+	var (
+		responseErr  *smb2.ResponseError
+		transportErr *smb2.TransportError
+		invalidErr   *smb2.InvalidResponseError
+	)
+	switch {
+	case errors.As(err, &responseErr):
+		switch responseErr.Code {
+		case statusLogonFailure:
+			return brute.FailureAuthRejected
+		case statusPasswordExpired, statusAccountDisabled, statusAccountLockedOut:
+			return brute.FailureLocked
+		}
+		return brute.FailureProtocolError
+	case errors.As(err, &transportErr):
+		return brute.ClassifyError(transportErr.Err)
+	case errors.As(err, &invalidErr):
+		return brute.FailureProtocolError
+	}
+	return brute.ClassifyError(err)
 }
@@ -3,100 +3,158 @@ package protocols
 import (
 	"context"
 	"crypto/tls"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/smtp"
+	"net/textproto"
+	"strings"
 	"time"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
 )
 
-// SMTPBrute SMTP爆破，支持 context 超时控制
+// SMTPBrute SMTP爆破，连接在任务项的上下文中建立，超时或取消时立即关闭
 func SMTPBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
+	if item.Username == "" {
+		return brute.NewResult(item).Unsupported("SMTP AUTH requires a username")
 	}
+	return authenticateOnce(item, newSMTPSession)
+}
+
+// smtpSession SMTP 会话，STARTTLS 只协商一次，AUTH 失败后可以继续尝试
+type smtpSession struct {
+	ctx    context.Context
+	dialer *sessionDialer
+	client *smtp.Client
+	host   string
+}
 
-	// 创建带超时的 context
-	ctx, cancel := context.WithTimeout(context.Background(), item.Timeout)
-	defer cancel()
+// newSMTPSession 连接 SMTP 服务器，服务器支持时升级到 STARTTLS
+func newSMTPSession(ctx context.Context, item *brute.BruteItem) (brute.Session, error) {
+	dialer := newSessionDialer(ctx, item.Timeout)
+	address := fmt.Sprintf("%s:%d", item.Target, item.Port)
 
-	// 使用 channel 来传递结果，支持 context 取消
-	type smtpResult struct {
-		success bool
-		err     error
-		banner  string
+	// 尝试连接SMTP服务器
+	conn, err := dialer.Dial("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("SMTP dial failed: %w", err)
+	}
+	dialer.extend(item.Timeout)
+	client, err := smtp.NewClient(conn, item.Target)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SMTP greeting failed: %w", err)
+	}
+
+	// 检查是否支持STARTTLS
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		config := &tls.Config{
+			ServerName:         item.Target,
+			InsecureSkipVerify: true,
+		}
+		if err := client.StartTLS(config); err != nil {
+			// STARTTLS失败，继续使用明文连接
+		}
 	}
+	return &smtpSession{ctx: ctx, dialer: dialer, client: client, host: item.Target}, nil
+}
 
-	resultChan := make(chan smtpResult, 1)
+func (s *smtpSession) Accepts(item *brute.BruteItem) bool {
+	return true
+}
 
-	// 在 goroutine 中执行 SMTP 操作
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				resultChan <- smtpResult{
-					success: false,
-					err:     fmt.Errorf("SMTP operation panic: %v", r),
-				}
-			}
-		}()
+func (s *smtpSession) Authenticate(item *brute.BruteItem) (*brute.BruteResult, bool) {
+	result := brute.NewResult(item)
+	if item.Username == "" {
+		return result.Unsupported("SMTP AUTH requires a username"), true
+	}
+	s.dialer.extend(item.Timeout)
 
-		address := fmt.Sprintf("%s:%d", item.Target, item.Port)
+	// 尝试认证
+	auth := smtp.PlainAuth("", item.Username, item.Password, item.Target)
+	if err := s.auth(auth); err != nil {
+		result = failWithContext(s.ctx, result, classifySMTPError(err), fmt.Errorf("SMTP auth failed: %w", err))
+		return result, result.FailureKind == brute.FailureAuthRejected
+	}
 
-		// 尝试连接SMTP服务器
-		client, err := smtp.Dial(address)
-		if err != nil {
-			resultChan <- smtpResult{
-				success: false,
-				err:     fmt.Errorf("SMTP dial failed: %w", err),
-			}
-			return
+	return result.Succeed("SMTP authentication successful"), false
+}
+
+// auth 与 smtp.Client.Auth 相同的 SASL 流程
+// smtp.Client.Auth 在认证失败后会发送 QUIT 并关闭连接，这里只中止本次 AUTH，服务端回到可以重新认证的状态
+func (s *smtpSession) auth(a smtp.Auth) error {
+	ok, mechanisms := s.client.Extension("AUTH")
+	if !ok {
+		return errors.New("smtp: server doesn't support AUTH")
+	}
+	_, isTLS := s.client.TLSConnectionState()
+	mech, resp, err := a.Start(&smtp.ServerInfo{Name: s.host, TLS: isTLS, Auth: strings.Fields(mechanisms)})
+	if err != nil {
+		return err
+	}
+
+	encoding := base64.StdEncoding
+	code, message, err := s.cmd(strings.TrimSpace(fmt.Sprintf("AUTH %s %s", mech, encoding.EncodeToString(resp))))
+	for err == nil {
+		var challenge []byte
+		switch code {
+		case 334:
+			challenge, err = encoding.DecodeString(message)
+		case 235:
+			challenge = []byte(message)
+		default:
+			return &textproto.Error{Code: code, Msg: message}
 		}
-		defer client.Close()
-
-		// 检查是否支持STARTTLS
-		if ok, _ := client.Extension("STARTTLS"); ok {
-			config := &tls.Config{
-				ServerName:         item.Target,
-				InsecureSkipVerify: true,
-			}
-			if err := client.StartTLS(config); err != nil {
-				// STARTTLS失败，继续使用明文连接
-			}
+		if err == nil {
+			resp, err = a.Next(challenge, code == 334)
 		}
-
-		// 尝试认证
-		auth := smtp.PlainAuth("", item.Username, item.Password, item.Target)
-		err = client.Auth(auth)
 		if err != nil {
-			resultChan <- smtpResult{
-				success: false,
-				err:     fmt.Errorf("SMTP auth failed: %w", err),
-			}
-			return
+			// 中止本次 AUTH
+			s.cmd("*")
+			return err
+		}
+		if resp == nil {
+			return nil
 		}
+		code, message, err = s.cmd(encoding.EncodeToString(resp))
+	}
+	return err
+}
+
+// cmd 发送一行命令并读取响应，响应码由调用方判断
+func (s *smtpSession) cmd(line string) (int, string, error) {
+	text := s.client.Text
+	id, err := text.Cmd("%s", line)
+	if err != nil {
+		return 0, "", err
+	}
+	text.StartResponse(id)
+	defer text.EndResponse(id)
+	return text.ReadResponse(0)
+}
 
-		resultChan <- smtpResult{
-			success: true,
-			banner:  "SMTP authentication successful",
+func (s *smtpSession) Close() error {
+	s.dialer.extend(time.Second)
+	return s.client.Close()
+}
+
+// classifySMTPError 根据 SMTP 响应码区分认证失败和不支持认证
+func classifySMTPError(err error) brute.FailureKind {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		switch protoErr.Code {
+		case 535, 534:
+			return brute.FailureAuthRejected
+		case 502, 503, 504:
+			return brute.FailureUnsupported
 		}
-	}()
-
-	// 等待结果或超时
-	select {
-	case smtpRes := <-resultChan:
-		result.Success = smtpRes.success
-		result.Error = smtpRes.err
-		result.Banner = smtpRes.banner
-		return result
-
-	case <-ctx.Done():
-		result.Error = fmt.Errorf("SMTP operation timeout after %v: %w", item.Timeout, ctx.Err())
-		return result
-
-	case <-time.After(item.Timeout + time.Second*2):
-		// 额外的安全超时，防止 context 失效
-		result.Error = fmt.Errorf("SMTP operation hard timeout after %v", item.Timeout+time.Second*2)
-		return result
+		return brute.FailureProtocolError
+	}
+	// net/smtp 在服务器未声明 AUTH 或要求加密时返回普通错误
+	if strings.Contains(err.Error(), "unencrypted connection") ||
+		strings.Contains(err.Error(), "doesn't support AUTH") {
+		return brute.FailureUnsupported
 	}
+	return brute.ClassifyError(err)
 }
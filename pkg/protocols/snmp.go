@@ -1,26 +1,21 @@
 package protocols
 
 import (
-	"context"
 	"fmt"
-	"time"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
 	"github.com/gosnmp/gosnmp"
+	"github.com/projectdiscovery/gologger"
 )
 
-// SNMPBrute SNMP爆破，支持 context 超时控制
+// SNMPBrute SNMP爆破，请求在任务项的上下文中发送，超时或取消时立即返回
 func SNMPBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
+	result := brute.NewResult(item)
 	if item.Username != "" {
-		return result
+		return result.Unsupported("SNMP authenticates with community only")
 	}
 
-	// 创建带超时的 context
-	ctx, cancel := context.WithTimeout(context.Background(), item.Timeout)
+	ctx, cancel := item.TimeoutContext()
 	defer cancel()
 
 	// SNMP使用community字符串，通常作为"密码"传入
@@ -29,85 +24,64 @@ func SNMPBrute(item *brute.BruteItem) *brute.BruteResult {
 		community = "public" // 默认community
 	}
 
-	// 使用 channel 来传递结果，支持 context 取消
-	type snmpResult struct {
-		success bool
-		err     error
-		banner  string
+	// 创建SNMP客户端
+	g := &gosnmp.GoSNMP{
+		Target:    item.Target,
+		Port:      uint16(item.Port),
+		Community: community,
+		Version:   gosnmp.Version2c,
+		Timeout:   item.Timeout / 2, // 使用一半的超时时间
+		Retries:   1,
+		Context:   ctx,
 	}
 
-	resultChan := make(chan snmpResult, 1)
-
-	// 在 goroutine 中执行 SNMP 操作
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				resultChan <- snmpResult{
-					success: false,
-					err:     fmt.Errorf("SNMP operation panic: %v", r),
-				}
-			}
-		}()
+	if err := g.Connect(); err != nil {
+		return result.FailWithError(fmt.Errorf("SNMP connect failed: %w", err))
+	}
+	defer g.Conn.Close()
 
-		// 创建SNMP客户端
-		g := &gosnmp.GoSNMP{
-			Target:    item.Target,
-			Port:      uint16(item.Port),
-			Community: community,
-			Version:   gosnmp.Version2c,
-			Timeout:   item.Timeout / 2, // 使用一半的超时时间
-			Retries:   1,
+	// 尝试获取系统信息 (sysDescr OID: 1.3.6.1.2.1.1.1.0)
+	oids := []string{"1.3.6.1.2.1.1.1.0"}
+	response, err := g.Get(oids)
+	if err != nil {
+		// 错误的 community 会被服务端直接丢弃，表现为请求超时，UDP 无连接，没有响应同样意味着 community 错误
+		kind := brute.ClassifyError(err)
+		if kind == brute.FailureTimeout || kind == brute.FailureUnknown {
+			kind = brute.FailureAuthRejected
 		}
+		return result.Fail(kind, fmt.Errorf("SNMP get failed: %w", err))
+	}
 
-		err := g.Connect()
-		if err != nil {
-			resultChan <- snmpResult{
-				success: false,
-				err:     fmt.Errorf("SNMP connect failed: %w", err),
-			}
-			return
-		}
-		defer g.Conn.Close()
+	if len(response.Variables) == 0 {
+		return result.AuthRejected(fmt.Errorf("SNMP community '%s' failed", community))
+	}
+	result.Succeed(fmt.Sprintf("SNMP community '%s' successful", community))
+	if item.Enrich {
+		enrichSNMP(g, result)
+	}
+	return result
+}
 
-		// 尝试获取系统信息 (sysDescr OID: 1.3.6.1.2.1.1.1.0)
-		oids := []string{"1.3.6.1.2.1.1.1.0"}
-		response, err := g.Get(oids)
-		if err != nil {
-			resultChan <- snmpResult{
-				success: false,
-				err:     fmt.Errorf("SNMP get failed: %w", err),
-			}
-			return
-		}
+// snmpSystemOIDs 采集的系统信息 OID
+var snmpSystemOIDs = map[string]string{
+	".1.3.6.1.2.1.1.1.0": "sys_descr",
+	".1.3.6.1.2.1.1.5.0": "sys_name",
+}
 
-		if len(response.Variables) > 0 {
-			resultChan <- snmpResult{
-				success: true,
-				banner:  fmt.Sprintf("SNMP community '%s' successful", community),
-			}
-		} else {
-			resultChan <- snmpResult{
-				success: false,
-				err:     fmt.Errorf("SNMP community '%s' failed", community),
-			}
+// enrichSNMP 采集 sysName 和 sysDescr
+func enrichSNMP(g *gosnmp.GoSNMP, result *brute.BruteResult) {
+	response, err := g.Get([]string{".1.3.6.1.2.1.1.1.0", ".1.3.6.1.2.1.1.5.0"})
+	if err != nil {
+		gologger.Debug().Msgf("SNMP enrichment failed for %s: %v", result.Item.Target, err)
+		return
+	}
+	for _, variable := range response.Variables {
+		key, ok := snmpSystemOIDs[variable.Name]
+		if !ok {
+			continue
+		}
+		if value, ok := variable.Value.([]byte); ok {
+			result.SetExtra(key, string(value))
 		}
-	}()
-
-	// 等待结果或超时
-	select {
-	case snmpRes := <-resultChan:
-		result.Success = snmpRes.success
-		result.Error = snmpRes.err
-		result.Banner = snmpRes.banner
-		return result
-
-	case <-ctx.Done():
-		result.Error = fmt.Errorf("SNMP operation timeout after %v: %w", item.Timeout, ctx.Err())
-		return result
-
-	case <-time.After(item.Timeout + time.Second*2):
-		// 额外的安全超时，防止 context 失效
-		result.Error = fmt.Errorf("SNMP operation hard timeout after %v", item.Timeout+time.Second*2)
-		return result
 	}
 }
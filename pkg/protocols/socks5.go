@@ -2,77 +2,75 @@ package protocols
 
 import (
 	"fmt"
-	"net"
+	"strings"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
 	"golang.org/x/net/proxy"
 )
 
-// SOCKS5Brute SOCKS5爆破
+// SOCKS5Brute SOCKS5爆破，连接在任务项的上下文中建立，超时或取消时立即关闭
 func SOCKS5Brute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
+	result := brute.NewResult(item)
 	if item.Username == "" {
-		return result
+		return result.Unsupported("SOCKS5 authentication requires a username")
 	}
-	// 构建SOCKS5服务器地址
-	socks5Addr := fmt.Sprintf("%s:%d", item.Target, item.Port)
-
-	// 方法1: 使用golang.org/x/net/proxy包进行SOCKS5认证
-	if item.Username != "" && item.Password != "" {
-		// 有用户名密码的认证
-		auth := &proxy.Auth{
-			User:     item.Username,
-			Password: item.Password,
-		}
+	auth := &proxy.Auth{
+		User:     item.Username,
+		Password: item.Password,
+	}
+	return socks5Connect(item, auth, "223.5.5.5:53") // ali DNS作为测试目标
+}
 
-		// 创建SOCKS5代理拨号器
-		dialer, err := proxy.SOCKS5("tcp", socks5Addr, auth, &net.Dialer{
-			Timeout: item.Timeout,
-		})
-		if err != nil {
-			result.Error = fmt.Errorf("failed to create SOCKS5 dialer: %w", err)
-			return result
-		}
+// SOCKS5Unauth 检查 SOCKS5 代理是否接受无认证的连接
+func SOCKS5Unauth(item *brute.BruteItem) *brute.BruteResult {
+	return socks5Connect(item, nil, "8.8.8.8:53")
+}
 
-		// 尝试通过代理连接到一个目标地址来验证认证
-		testAddr := "223.5.5.5:53" // ali DNS作为测试目标
-		conn, err := dialer.Dial("tcp", testAddr)
-		if err != nil {
-			// 认证失败或连接失败
-			result.Error = err
-			return result
-		}
-		defer conn.Close()
+// socks5Connect 通过代理连接测试地址，auth 为 nil 时只提供无认证方式
+func socks5Connect(item *brute.BruteItem, auth *proxy.Auth, testAddr string) *brute.BruteResult {
+	result := brute.NewResult(item)
+	// 构建SOCKS5服务器地址
+	socks5Addr := fmt.Sprintf("%s:%d", item.Target, item.Port)
 
-		// 如果能成功建立连接，说明认证成功
-		result.Success = true
-		result.Banner = fmt.Sprintf("SOCKS5 authentication successful for %s:%s", item.Username, item.Password)
+	ctx, cancel := item.TimeoutContext()
+	defer cancel()
 
-	} else {
-		// 无认证的SOCKS5代理测试
-		dialer, err := proxy.SOCKS5("tcp", socks5Addr, nil, &net.Dialer{
-			Timeout: item.Timeout,
-		})
-		if err != nil {
-			result.Error = fmt.Errorf("failed to create SOCKS5 dialer: %w", err)
-			return result
-		}
+	// 创建SOCKS5代理拨号器，到代理的连接绑定到任务项的上下文
+	dialer, err := proxy.SOCKS5("tcp", socks5Addr, auth, utils.NewDialer(ctx, item.Timeout))
+	if err != nil {
+		return result.Fail(brute.FailureUnknown, fmt.Errorf("failed to create SOCKS5 dialer: %w", err))
+	}
 
-		// 尝试通过代理连接
-		testAddr := "8.8.8.8:53"
-		conn, err := dialer.Dial("tcp", testAddr)
-		if err != nil {
-			result.Error = err
-			return result
-		}
-		defer conn.Close()
+	// 尝试通过代理连接到一个目标地址来验证认证
+	conn, err := dialer.(proxy.ContextDialer).DialContext(ctx, "tcp", testAddr)
+	if err != nil {
+		// 认证失败或连接失败
+		return result.Fail(classifySOCKS5Error(err), err)
+	}
+	defer conn.Close()
 
-		result.Success = true
-		result.Banner = "SOCKS5 proxy connection successful (no auth)"
+	// 如果能成功建立连接，说明认证成功
+	if auth == nil {
+		return result.Succeed("SOCKS5 proxy connection successful (no auth)")
 	}
+	return result.Succeed(fmt.Sprintf("SOCKS5 authentication successful for %s:%s", item.Username, item.Password))
+}
 
-	return result
+// classifySOCKS5Error 区分 SOCKS5 认证失败、认证方式不匹配和代理连接失败
+func classifySOCKS5Error(err error) brute.FailureKind {
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "username/password authentication failed"),
+		strings.Contains(message, "invalid username/password"):
+		return brute.FailureAuthRejected
+	case strings.Contains(message, "no acceptable authentication methods"),
+		strings.Contains(message, "unsupported authentication method"):
+		return brute.FailureUnsupported
+	case strings.Contains(message, "unexpected protocol version"),
+		strings.Contains(message, "unknown error"):
+		// unknown error 为代理返回的 CONNECT 失败应答，说明已经通过了认证阶段
+		return brute.FailureProtocolError
+	}
+	return brute.ClassifyError(err)
 }
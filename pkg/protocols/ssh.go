@@ -2,102 +2,223 @@ package protocols
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"time"
+	"net"
+	"strings"
+	"sync"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
 	"github.com/projectdiscovery/gologger"
 	"golang.org/x/crypto/ssh"
 )
 
-// SSHBrute SSH爆破，支持 context 超时控制
+// SSHBrute SSH爆破，连接在任务项的上下文中建立，超时或取消时立即关闭
 func SSHBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
+	result := brute.NewResult(item)
 	if item.Username == "" {
-		return result
+		return result.Unsupported("SSH requires a username")
 	}
 
-	// 创建带超时的 context
-	ctx, cancel := context.WithTimeout(context.Background(), item.Timeout)
+	ctx, cancel := item.TimeoutContext()
 	defer cancel()
 
-	// 使用 channel 来传递结果，支持 context 取消
-	type sshResult struct {
-		success bool
-		err     error
-		banner  string
-	}
-
-	resultChan := make(chan sshResult, 1)
-
-	// 在 goroutine 中执行 SSH 操作
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				resultChan <- sshResult{
-					success: false,
-					err:     fmt.Errorf("SSH operation panic: %v", r),
-				}
-			}
-		}()
-
-		config := &ssh.ClientConfig{
-			User: item.Username,
-			Auth: []ssh.AuthMethod{
-				ssh.Password(item.Password),
-			},
-			Timeout:         item.Timeout / 2, // 给SSH内部操作一半的时间
-			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		}
+	config := &ssh.ClientConfig{
+		User: item.Username,
+		Auth: []ssh.AuthMethod{
+			ssh.Password(item.Password),
+		},
+		Timeout:         item.Timeout,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+
+	target := fmt.Sprintf("%s:%d", item.Target, item.Port)
+	gologger.Debug().Msgf("Attempting SSH connection to %s with user %s password %s", target, item.Username, item.Password)
+
+	conn, err := utils.DialContext(ctx, "tcp", target, item.Timeout)
+	if err != nil {
+		return result.FailWithError(fmt.Errorf("SSH dial failed: %w", err))
+	}
+	defer conn.Close()
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, target, config)
+	if err != nil {
+		return result.Fail(classifySSHError(err), fmt.Errorf("SSH handshake failed: %w", err))
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+
+	// 创建一个简单的session来验证连接
+	session, err := client.NewSession()
+	if err != nil {
+		return result.FailWithError(fmt.Errorf("SSH session creation failed: %w", err))
+	}
+	session.Close()
+
+	result.Succeed("SSH connection successful")
+	if item.Enrich {
+		enrichSSH(client, result)
+	}
+	return result
+}
+
+// enrichSSH 采集服务端版本和 uname -a 的输出，Windows 等没有 uname 的系统只记录版本
+func enrichSSH(client *ssh.Client, result *brute.BruteResult) {
+	result.SetExtra("server_version", string(client.ServerVersion()))
+	session, err := client.NewSession()
+	if err != nil {
+		gologger.Debug().Msgf("SSH enrichment failed for %s: %v", result.Item.Target, err)
+		return
+	}
+	defer session.Close()
+	output, err := session.Output("uname -a")
+	if err != nil {
+		gologger.Debug().Msgf("SSH enrichment failed for %s: %v", result.Item.Target, err)
+		return
+	}
+	result.SetExtra("uname", strings.TrimSpace(string(output)))
+}
+
+// errSSHSessionClosed 会话关闭时结束等待密码的握手
+var errSSHSessionClosed = errors.New("ssh session closed")
+
+// sshSession SSH 会话，在同一个握手中连续尝试密码，直到服务端断开或达到 MaxAuthTries
+// SSH 的用户名在握手开始时确定，会话只能尝试同一个用户名
+type sshSession struct {
+	username  string
+	dialer    *sessionDialer
+	passwords chan string   // 交给握手尝试的密码
+	rejected  chan struct{} // 上一个密码被拒绝，握手在等待下一个密码
+	done      chan struct{} // 握手结束
+	closeOnce sync.Once
+
+	// 握手协程写入，done 关闭后读取
+	client *ssh.Client
+	err    error
+}
+
+// newSSHSession 连接 SSH 服务器并在后台进行握手，密码由 Authenticate 逐个提供
+func newSSHSession(ctx context.Context, item *brute.BruteItem) (brute.Session, error) {
+	dialer := newSessionDialer(ctx, item.Timeout)
+	target := fmt.Sprintf("%s:%d", item.Target, item.Port)
+	conn, err := dialer.Dial("tcp", target)
+	if err != nil {
+		return nil, fmt.Errorf("SSH dial failed: %w", err)
+	}
+	dialer.extend(item.Timeout)
+
+	s := &sshSession{
+		username:  item.Username,
+		dialer:    dialer,
+		passwords: make(chan string),
+		rejected:  make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	go s.handshake(conn, target, item)
+	return s, nil
+}
 
-		target := fmt.Sprintf("%s:%d", item.Target, item.Port)
-		gologger.Debug().Msgf("Attempting SSH connection to %s with user %s password %s", target, item.Username, item.Password)
+// handshake 进行 SSH 握手，密码被拒绝后继续向会话索取下一个密码
+func (s *sshSession) handshake(conn net.Conn, target string, item *brute.BruteItem) {
+	defer close(s.done)
 
-		client, err := ssh.Dial("tcp", target, config)
-		if err != nil {
-			resultChan <- sshResult{
-				success: false,
-				err:     fmt.Errorf("SSH dial failed: %w", err),
-			}
-			return
+	pending := false
+	next := func() (string, error) {
+		if pending {
+			s.rejected <- struct{}{}
 		}
-		defer client.Close()
-
-		// 创建一个简单的session来验证连接
-		session, err := client.NewSession()
-		if err != nil {
-			resultChan <- sshResult{
-				success: false,
-				err:     fmt.Errorf("SSH session creation failed: %w", err),
-			}
-			return
+		password, ok := <-s.passwords
+		if !ok {
+			return "", errSSHSessionClosed
 		}
-		defer session.Close()
+		pending = true
+		return password, nil
+	}
 
-		resultChan <- sshResult{
-			success: true,
-			banner:  "SSH connection successful",
-		}
-	}()
+	config := &ssh.ClientConfig{
+		User:            item.Username,
+		Auth:            []ssh.AuthMethod{ssh.RetryableAuthMethod(ssh.PasswordCallback(next), -1)},
+		Timeout:         item.Timeout,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, target, config)
+	if err != nil {
+		conn.Close()
+		s.err = err
+		return
+	}
+	s.client = ssh.NewClient(sshConn, chans, reqs)
+}
+
+func (s *sshSession) Accepts(item *brute.BruteItem) bool {
+	return item.Username == s.username
+}
+
+func (s *sshSession) Authenticate(item *brute.BruteItem) (*brute.BruteResult, bool) {
+	result := brute.NewResult(item)
+	if item.Username == "" {
+		return result.Unsupported("SSH requires a username"), false
+	}
+	s.dialer.extend(item.Timeout)
+	gologger.Debug().Msgf("Attempting SSH password for user %s password %s over session", item.Username, item.Password)
 
-	// 等待结果或超时
 	select {
-	case sshRes := <-resultChan:
-		result.Success = sshRes.success
-		result.Error = sshRes.err
-		result.Banner = sshRes.banner
-		return result
+	case s.passwords <- item.Password:
+	case <-s.done:
+		return s.fail(result), false
+	}
 
-	case <-ctx.Done():
-		result.Error = fmt.Errorf("SSH operation timeout after %v: %w", item.Timeout, ctx.Err())
-		return result
+	select {
+	case <-s.rejected:
+		return result.AuthRejected(errors.New("SSH password rejected")), true
+	case <-s.done:
+	}
+	if s.err != nil {
+		return s.fail(result), false
+	}
+
+	// 创建一个简单的session来验证连接
+	session, err := s.client.NewSession()
+	if err != nil {
+		return result.FailWithError(fmt.Errorf("SSH session creation failed: %w", err)), false
+	}
+	session.Close()
+	result.Succeed("SSH connection successful")
+	if item.Enrich {
+		enrichSSH(s.client, result)
+	}
+	return result, false
+}
+
+// fail 记录握手失败
+func (s *sshSession) fail(result *brute.BruteResult) *brute.BruteResult {
+	return result.Fail(classifySSHError(s.err), fmt.Errorf("SSH handshake failed: %w", s.err))
+}
+
+func (s *sshSession) Close() error {
+	s.closeOnce.Do(func() { close(s.passwords) })
+	if s.dialer.conn != nil {
+		s.dialer.conn.Close()
+	}
+	<-s.done
+	if s.client != nil {
+		return s.client.Close()
+	}
+	return nil
+}
 
-	case <-time.After(item.Timeout + time.Second*2):
-		// 额外的安全超时，防止 context 失效
-		result.Error = fmt.Errorf("SSH operation hard timeout after %v", item.Timeout+time.Second*2)
-		return result
+// classifySSHError 区分 SSH 认证失败和握手失败
+func classifySSHError(err error) brute.FailureKind {
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "unable to authenticate"),
+		strings.Contains(strings.ToLower(message), "too many authentication failures"):
+		return brute.FailureAuthRejected
+	case strings.Contains(message, "no common algorithm"),
+		strings.Contains(message, "version string"),
+		strings.Contains(message, "invalid packet"):
+		return brute.FailureProtocolError
 	}
+	return brute.ClassifyError(err)
 }
@@ -2,97 +2,62 @@ package telnet
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
 )
 
-// TelnetBrute Telnet爆破，支持 context 超时控制
+// TelnetBrute Telnet爆破，连接在任务项的上下文中建立，超时或取消时立即关闭
 func TelnetBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
+	result := brute.NewResult(item)
 
-	// 创建带超时的 context
-	ctx, cancel := context.WithTimeout(context.Background(), item.Timeout)
+	ctx, cancel := item.TimeoutContext()
 	defer cancel()
 
-	// 使用 channel 来传递结果，支持 context 取消
-	type telnetResult struct {
-		success bool
-		err     error
-		banner  string
+	serverType, err := getTelnetServerType(ctx, item.Target, item.Port, item.Timeout)
+	if err != nil {
+		return result.FailWithError(fmt.Errorf("Telnet connect failed: %w", err))
 	}
 
-	resultChan := make(chan telnetResult, 1)
-
-	// 在 goroutine 中执行 Telnet 操作
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				resultChan <- telnetResult{
-					success: false,
-					err:     fmt.Errorf("Telnet operation panic: %v", r),
-				}
-			}
-		}()
-
-		client := New(item.Target, item.Port, item.Timeout)
-		err := client.Connect()
-		if err != nil {
-			resultChan <- telnetResult{
-				success: false,
-				err:     fmt.Errorf("Telnet connect failed: %w", err),
-			}
-			return
-		}
-		defer client.Close()
-
-		client.UserName = item.Username
-		client.Password = item.Password
-		client.ServerType = getTelnetServerType(item.Target, item.Port, item.Timeout)
-		err = client.Login()
-		if err != nil {
-			resultChan <- telnetResult{
-				success: false,
-				err:     fmt.Errorf("Telnet login failed: %w", err),
-			}
-			return
-		}
-
-		resultChan <- telnetResult{
-			success: true,
-			banner:  "Telnet login successful",
-		}
-	}()
+	client := New(item.Target, item.Port, item.Timeout)
+	if err := client.ConnectContext(ctx); err != nil {
+		return result.FailWithError(fmt.Errorf("Telnet connect failed: %w", err))
+	}
+	defer client.Close()
 
-	// 等待结果或超时
-	select {
-	case telnetRes := <-resultChan:
-		result.Success = telnetRes.success
-		result.Error = telnetRes.err
-		result.Banner = telnetRes.banner
-		return result
+	client.UserName = item.Username
+	client.Password = item.Password
+	client.ServerType = serverType
+	if err := client.Login(); err != nil {
+		return result.Fail(classifyLoginError(err), fmt.Errorf("Telnet login failed: %w", err))
+	}
 
-	case <-ctx.Done():
-		result.Error = fmt.Errorf("Telnet operation timeout after %v: %w", item.Timeout, ctx.Err())
-		return result
+	return result.Succeed("Telnet login successful")
+}
 
-	case <-time.After(item.Timeout + time.Second*2):
-		// 额外的安全超时，防止 context 失效
-		result.Error = fmt.Errorf("Telnet operation hard timeout after %v", item.Timeout+time.Second*2)
-		return result
+// classifyLoginError 推断 Telnet 登录错误的失败类型
+func classifyLoginError(err error) brute.FailureKind {
+	switch {
+	case errors.Is(err, ErrLoginFailed):
+		return brute.FailureAuthRejected
+	case errors.Is(err, ErrServiceDisabled):
+		return brute.FailureProtocolError
 	}
+	return brute.ClassifyError(err)
 }
 
-func getTelnetServerType(ip string, port int, timeout time.Duration) int {
+// getTelnetServerType 使用单独的连接识别登录提示的类型
+func getTelnetServerType(ctx context.Context, ip string, port int, timeout time.Duration) (int, error) {
 	client := New(ip, port, timeout)
-	err := client.Connect()
-	if err != nil {
-		return Closed
+	if err := client.ConnectContext(ctx); err != nil {
+		return Closed, err
 	}
 	defer client.Close()
-	return client.MakeServerType()
+	serverType := client.MakeServerType()
+	if err := ctx.Err(); err != nil {
+		return Closed, err
+	}
+	return serverType, nil
 }
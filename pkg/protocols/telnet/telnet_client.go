@@ -2,12 +2,15 @@ package telnet
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net"
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
 )
 
 const (
@@ -116,6 +119,7 @@ type Client struct {
 	LastResponse string
 	ServerType   int
 	Timeout      time.Duration
+	ctx          context.Context // 连接的上下文，结束时关闭连接并中断等待
 }
 
 func New(addr string, port int, timeout time.Duration) *Client {
@@ -132,7 +136,13 @@ func New(addr string, port int, timeout time.Duration) *Client {
 }
 
 func (c *Client) Connect() error {
-	conn, err := net.DialTimeout("tcp", c.Netloc(), c.Timeout)
+	return c.ConnectContext(context.Background())
+}
+
+// ConnectContext 在上下文中建立连接，上下文取消或超时时关闭连接，登录过程中的等待也随之结束
+func (c *Client) ConnectContext(ctx context.Context) error {
+	c.ctx = ctx
+	conn, err := utils.DialContext(ctx, "tcp", c.Netloc(), c.Timeout)
 	if err != nil {
 		return err
 	}
@@ -162,10 +172,24 @@ func (c *Client) Connect() error {
 		}
 	}()
 	//等待初始化
-	time.Sleep(time.Second * 3)
+	c.wait(time.Second * 3)
 	return nil
 }
 
+// wait 等待服务端响应，上下文结束时立即返回
+func (c *Client) wait(d time.Duration) {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
 func (c *Client) WriteContext(s string) {
 	_ = c.write([]byte(s + "\x0d\x00"))
 }
@@ -173,7 +197,7 @@ func (c *Client) WriteContext(s string) {
 func (c *Client) ReadContext() string {
 	defer func() { c.Clear() }() //结束时，清空输出内容
 	if c.LastResponse == "" {
-		time.Sleep(time.Second)
+		c.wait(time.Second)
 	}
 	c.LastResponse = strings.ReplaceAll(c.LastResponse, "\x0d\x00", "")
 	c.LastResponse = strings.ReplaceAll(c.LastResponse, "\x0d\x0a", "\n")
@@ -340,10 +364,25 @@ func (c *Client) write(buf []byte) error {
 	return nil
 }
 
+// ErrLoginFailed 服务端拒绝了凭据
+var ErrLoginFailed = errors.New("login failed")
+
+// ErrServiceDisabled 无法识别登录提示，服务不可用
+var ErrServiceDisabled = errors.New("service is disabled")
+
 func (c *Client) Login() error {
+	err := c.login()
+	// 上下文结束后读写均已失败，不能据此判断凭据是否正确
+	if c.ctx != nil && c.ctx.Err() != nil {
+		return c.ctx.Err()
+	}
+	return err
+}
+
+func (c *Client) login() error {
 	switch c.ServerType {
 	case Closed:
-		return errors.New("service is disabled")
+		return ErrServiceDisabled
 	case UnauthorizedAccess:
 		return nil
 	case OnlyPassword:
@@ -389,11 +428,11 @@ func (c *Client) loginForOnlyPassword() error {
 	c.Clear()
 	//清空一次输出
 	c.WriteContext(c.Password)
-	time.Sleep(time.Second * 3)
+	c.wait(time.Second * 3)
 
 	responseString := c.ReadContext()
 	if c.isLoginFailed(responseString) {
-		return errors.New("login failed")
+		return ErrLoginFailed
 	}
 
 	if c.isLoginSucceed(responseString) {
@@ -401,27 +440,27 @@ func (c *Client) loginForOnlyPassword() error {
 	}
 
 	//slog.Println(slog.WARN, c.IPAddr, c.Port, "|", responseString)
-	return errors.New("login failed")
+	return ErrLoginFailed
 
 }
 
 func (c *Client) loginForUsernameAndPassword() error {
 	c.WriteContext(c.UserName)
-	time.Sleep(time.Second * 3)
+	c.wait(time.Second * 3)
 	c.Clear() //清空一次输出
 	c.WriteContext(c.Password)
-	time.Sleep(time.Second * 3)
+	c.wait(time.Second * 3)
 
 	responseString := c.ReadContext()
 	// fmt.Println("responseString:", responseString)
 	if c.isLoginFailed(responseString) {
-		return errors.New("login failed")
+		return ErrLoginFailed
 	}
 	if c.isLoginSucceed(responseString) {
 		return nil
 	}
 	//slog.Println(slog.WARN, c.IPAddr, c.Port, "|", responseString)
-	return errors.New("login failed")
+	return ErrLoginFailed
 }
 
 func (c *Client) Clear() {
@@ -474,7 +513,7 @@ func (c *Client) isLoginSucceed(responseString string) bool {
 	}
 	c.Clear()
 	c.WriteContext("?")
-	time.Sleep(time.Second * 3)
+	c.wait(time.Second * 3)
 	responseString = c.ReadContext()
 	if strings.Count(responseString, "\n") > 6 {
 		//slog.Println(slog.WARN, "3|", c.IPAddr, c.Port, responseString)
@@ -0,0 +1,65 @@
+package protocols
+
+import (
+	"fmt"
+
+	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
+	"github.com/mitchellh/go-vnc"
+)
+
+// withCredentials 复制任务项并替换凭据，探测时不修改引擎传入的任务项
+func withCredentials(item *brute.BruteItem, username, password string) *brute.BruteItem {
+	probe := *item
+	probe.Username = username
+	probe.Password = password
+	return &probe
+}
+
+// RedisUnauth 检查 Redis 是否未设置 requirepass
+func RedisUnauth(item *brute.BruteItem) *brute.BruteResult {
+	return RedisBrute(withCredentials(item, "", ""))
+}
+
+// FTPUnauth 检查 FTP 是否允许匿名登录
+func FTPUnauth(item *brute.BruteItem) *brute.BruteResult {
+	return FTPBrute(withCredentials(item, "anonymous", "anonymous@"))
+}
+
+// SNMPUnauth 检查 SNMP 是否使用默认的 public 或 private community
+func SNMPUnauth(item *brute.BruteItem) *brute.BruteResult {
+	var result *brute.BruteResult
+	for _, community := range []string{"public", "private"} {
+		result = SNMPBrute(withCredentials(item, "", community))
+		if result.Success {
+			break
+		}
+	}
+	return result
+}
+
+// VNCUnauth 检查 VNC 是否提供 None 安全类型
+func VNCUnauth(item *brute.BruteItem) *brute.BruteResult {
+	result := brute.NewResult(item)
+	ctx, cancel := item.TimeoutContext()
+	defer cancel()
+
+	address := fmt.Sprintf("%s:%d", item.Target, item.Port)
+	conn, err := utils.DialContext(ctx, "tcp", address, item.Timeout)
+	if err != nil {
+		return result.FailWithError(fmt.Errorf("failed to connect to VNC server: %w", err))
+	}
+	defer conn.Close()
+
+	// 只提供 None 安全类型，服务端要求密码时握手以 no suitable auth schemes 失败
+	client, err := vnc.Client(conn, &vnc.ClientConfig{
+		Auth:      []vnc.ClientAuth{new(vnc.ClientAuthNone)},
+		Exclusive: false,
+	})
+	if err != nil {
+		return result.Fail(classifyVNCError(err), fmt.Errorf("VNC handshake failed: %w", err))
+	}
+	defer client.Close()
+
+	return result.Succeed("VNC allows access without authentication")
+}
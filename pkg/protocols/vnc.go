@@ -1,36 +1,30 @@
 package protocols
 
 import (
-	"context"
 	"fmt"
-	"net"
+	"strings"
 
 	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
 	"github.com/mitchellh/go-vnc"
 )
 
-// VNCBrute VNC爆破
+// VNCBrute VNC爆破，连接在任务项的上下文中建立，超时或取消时立即关闭
 func VNCBrute(item *brute.BruteItem) *brute.BruteResult {
-	result := &brute.BruteResult{
-		Item:    item,
-		Success: false,
-	}
+	result := brute.NewResult(item)
 	if item.Username != "" {
 		// VNC一般不使用用户名认证
-		return result
+		return result.Unsupported("VNC authenticates with password only")
 	}
-	// 创建带超时的上下文
-	ctx, cancel := context.WithTimeout(context.Background(), item.Timeout)
+	ctx, cancel := item.TimeoutContext()
 	defer cancel()
 
 	address := fmt.Sprintf("%s:%d", item.Target, item.Port)
 
-	// 使用上下文控制的连接
-	var d net.Dialer
-	conn, err := d.DialContext(ctx, "tcp", address)
+	// 握手阻塞在读写上时随连接关闭返回
+	conn, err := utils.DialContext(ctx, "tcp", address, item.Timeout)
 	if err != nil {
-		result.Error = fmt.Errorf("failed to connect to VNC server: %w", err)
-		return result
+		return result.FailWithError(fmt.Errorf("failed to connect to VNC server: %w", err))
 	}
 	defer conn.Close()
 
@@ -42,32 +36,27 @@ func VNCBrute(item *brute.BruteItem) *brute.BruteResult {
 		Exclusive: false,
 	}
 
-	// 使用goroutine和select来控制VNC握手超时
-	type vncResult struct {
-		client *vnc.ClientConn
-		err    error
+	client, err := vnc.Client(conn, cfg)
+	if err != nil {
+		return result.Fail(classifyVNCError(err), fmt.Errorf("VNC authentication failed: %w", err))
 	}
+	defer client.Close()
 
-	vncChan := make(chan vncResult, 1)
-	go func() {
-		client, err := vnc.Client(conn, cfg)
-		vncChan <- vncResult{client: client, err: err}
-	}()
-
-	select {
-	case vncRes := <-vncChan:
-		if vncRes.err != nil {
-			result.Error = fmt.Errorf("VNC authentication failed: %w", vncRes.err)
-			return result
-		}
-		defer vncRes.client.Close()
-
-		result.Success = true
-		result.Banner = "VNC authentication successful"
-		return result
+	return result.Succeed("VNC authentication successful")
+}
 
-	case <-ctx.Done():
-		result.Error = fmt.Errorf("VNC connection timeout: %w", ctx.Err())
-		return result
+// classifyVNCError 区分 VNC 握手失败和认证失败
+// 多次失败后服务端返回的 "Too many authentication failures" 由 ClassifyAuthError 识别为锁定
+func classifyVNCError(err error) brute.FailureKind {
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "no suitable auth schemes"):
+		return brute.FailureUnsupported
+	case strings.Contains(message, "ProtocolVersion"),
+		strings.Contains(message, "unsupported major version"),
+		strings.Contains(message, "unsupported minor version"),
+		strings.Contains(message, "no security types"):
+		return brute.FailureProtocolError
 	}
+	return brute.ClassifyAuthError(err)
 }
@@ -0,0 +1,132 @@
+package protocols
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
+)
+
+// zookeeperOpGetChildren 列出子节点的请求类型
+const zookeeperOpGetChildren = 8
+
+// ZooKeeperBrute ZooKeeper 的 digest 认证在 addauth 时总是成功，权限在访问节点时才检查，没有可以爆破的认证
+func ZooKeeperBrute(item *brute.BruteItem) *brute.BruteResult {
+	return brute.NewResult(item).Unsupported("ZooKeeper has no password authentication to brute force")
+}
+
+// ZooKeeperUnauth 检查 ZooKeeper 是否允许未认证的客户端列出根节点
+func ZooKeeperUnauth(item *brute.BruteItem) *brute.BruteResult {
+	result := brute.NewResult(item)
+	ctx, cancel := item.TimeoutContext()
+	defer cancel()
+
+	address := fmt.Sprintf("%s:%d", item.Target, item.Port)
+	conn, err := utils.DialContext(ctx, "tcp", address, item.Timeout)
+	if err != nil {
+		return result.FailWithError(fmt.Errorf("ZooKeeper dial failed: %w", err))
+	}
+	defer conn.Close()
+
+	// ConnectRequest: protocolVersion, lastZxidSeen, timeOut, sessionId, passwd, readOnly
+	connect := binary.BigEndian.AppendUint32(nil, 0)
+	connect = binary.BigEndian.AppendUint64(connect, 0)
+	connect = binary.BigEndian.AppendUint32(connect, 30000)
+	connect = binary.BigEndian.AppendUint64(connect, 0)
+	connect = binary.BigEndian.AppendUint32(connect, 16)
+	connect = append(connect, make([]byte, 16)...)
+	connect = append(connect, 0)
+	if err := writeZooKeeperPacket(conn, connect); err != nil {
+		return result.FailWithError(fmt.Errorf("ZooKeeper connect failed: %w", err))
+	}
+	response, err := readZooKeeperPacket(conn)
+	if err != nil {
+		return result.FailWithError(fmt.Errorf("ZooKeeper connect failed: %w", err))
+	}
+	// 服务端拒绝会话时返回的超时时间为 0
+	if len(response) < 8 || binary.BigEndian.Uint32(response[4:8]) == 0 {
+		return result.Fail(brute.FailureProtocolError, errors.New("ZooKeeper refused the session"))
+	}
+
+	// GetChildrenRequest: xid, type, path, watch
+	path := "/"
+	request := binary.BigEndian.AppendUint32(nil, 1)
+	request = binary.BigEndian.AppendUint32(request, zookeeperOpGetChildren)
+	request = binary.BigEndian.AppendUint32(request, uint32(len(path)))
+	request = append(request, path...)
+	request = append(request, 0)
+	if err := writeZooKeeperPacket(conn, request); err != nil {
+		return result.FailWithError(fmt.Errorf("ZooKeeper request failed: %w", err))
+	}
+	response, err = readZooKeeperPacket(conn)
+	if err != nil {
+		return result.FailWithError(fmt.Errorf("ZooKeeper request failed: %w", err))
+	}
+
+	// ReplyHeader: xid, zxid, err
+	if len(response) < 16 {
+		return result.Fail(brute.FailureProtocolError, errors.New("short ZooKeeper reply"))
+	}
+	if code := int32(binary.BigEndian.Uint32(response[12:16])); code != 0 {
+		return result.AuthRejected(fmt.Errorf("ZooKeeper denied listing / (error %d)", code))
+	}
+
+	result.Succeed("ZooKeeper allows access without authentication")
+	if item.Enrich {
+		if children, err := parseZooKeeperStrings(response[16:]); err == nil {
+			result.SetExtra("znodes", children)
+		}
+	}
+	return result
+}
+
+// writeZooKeeperPacket 写入带长度前缀的数据包
+func writeZooKeeperPacket(conn net.Conn, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	_, err := conn.Write(append(packet, payload...))
+	return err
+}
+
+// readZooKeeperPacket 读取带长度前缀的数据包
+func readZooKeeperPacket(conn net.Conn) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	if length > 1<<20 {
+		return nil, fmt.Errorf("ZooKeeper packet too large: %d", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// parseZooKeeperStrings 解析 jute 编码的字符串列表
+func parseZooKeeperStrings(data []byte) ([]string, error) {
+	if len(data) < 4 {
+		return nil, errors.New("short ZooKeeper string vector")
+	}
+	count := int(int32(binary.BigEndian.Uint32(data)))
+	data = data[4:]
+	values := make([]string, 0, max(count, 0))
+	for range count {
+		if len(data) < 4 {
+			return nil, errors.New("short ZooKeeper string")
+		}
+		length := int(binary.BigEndian.Uint32(data))
+		data = data[4:]
+		if length > len(data) {
+			return nil, errors.New("short ZooKeeper string")
+		}
+		values = append(values, string(data[:length]))
+		data = data[length:]
+	}
+	return values, nil
+}
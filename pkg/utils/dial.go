@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Dialer 绑定上下文的拨号器，建立的连接在上下文取消或超时时关闭
+// 协议库内部阻塞在读写上的协程会随连接关闭返回，不会在任务结束后继续运行
+type Dialer struct {
+	Context context.Context // 连接的生命周期，为空时不随上下文关闭
+	Timeout time.Duration   // 建立连接的超时时间，为 0 时只受上下文限制
+}
+
+// NewDialer 创建绑定上下文的拨号器
+func NewDialer(ctx context.Context, timeout time.Duration) *Dialer {
+	return &Dialer{Context: ctx, Timeout: timeout}
+}
+
+// Dial 建立连接，适用于只接受 Dial(network, address) 的协议库
+func (d *Dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(d.context(), network, address)
+}
+
+// DialContext 建立连接，ctx 只限制拨号过程，连接的生命周期绑定到拨号器的上下文
+// 协议库传入的 ctx 可能在拨号完成后即被取消，不能用于控制连接本身
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(d.context(), cancel)
+	defer stop()
+
+	dialer := &net.Dialer{Timeout: d.Timeout}
+	conn, err := dialer.DialContext(dialCtx, network, address)
+	if err != nil {
+		if cause := d.context().Err(); cause != nil {
+			return nil, fmt.Errorf("%w: %w", cause, err)
+		}
+		return nil, err
+	}
+	return bindContext(d.context(), conn), nil
+}
+
+// context 返回拨号器的上下文
+func (d *Dialer) context() context.Context {
+	if d.Context == nil {
+		return context.Background()
+	}
+	return d.Context
+}
+
+// DialContext 在上下文中建立连接，连接在上下文取消或超时时关闭
+func DialContext(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
+	return NewDialer(ctx, timeout).DialContext(ctx, network, address)
+}
+
+// contextConn 在上下文结束时关闭的连接
+type contextConn struct {
+	net.Conn
+	ctx  context.Context
+	stop func() bool
+}
+
+// bindContext 将连接的生命周期绑定到上下文
+func bindContext(ctx context.Context, conn net.Conn) net.Conn {
+	if ctx.Done() == nil {
+		return conn
+	}
+	return &contextConn{
+		Conn: conn,
+		ctx:  ctx,
+		stop: context.AfterFunc(ctx, func() { conn.Close() }),
+	}
+}
+
+func (c *contextConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	return n, c.contextError(err)
+}
+
+func (c *contextConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	return n, c.contextError(err)
+}
+
+func (c *contextConn) Close() error {
+	c.stop()
+	return c.Conn.Close()
+}
+
+// contextError 上下文结束导致的读写错误附带取消原因，便于区分超时、取消和连接重置
+func (c *contextConn) contextError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if cause := c.ctx.Err(); cause != nil {
+		return fmt.Errorf("%w: %w", cause, err)
+	}
+	return err
+}
@@ -2,12 +2,15 @@ package utils
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -85,13 +88,39 @@ func LoadLinesFromFile(filename string) ([]string, error) {
 
 // IsPortOpen 检查端口是否开放
 func IsPortOpen(host string, port int, timeout time.Duration) bool {
-	target := fmt.Sprintf("%s:%d", host, port)
-	conn, err := net.DialTimeout("tcp", target, timeout)
+	return ProbePort(context.Background(), "tcp", host, port, timeout) == nil
+}
+
+// ProbePort 探测端口是否可达，返回 nil 表示可达
+// TCP 以完成握手为准；UDP 没有握手，只有收到 ICMP 端口不可达时才判定为关闭，没有响应视为可达
+func ProbePort(ctx context.Context, network, host string, port int, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(host, strconv.Itoa(port)))
 	if err != nil {
-		return false
+		return err
 	}
 	defer conn.Close()
-	return true
+
+	if !strings.HasPrefix(network, "udp") {
+		return nil
+	}
+
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	if _, err := conn.Write([]byte{0}); err != nil {
+		return err
+	}
+	if _, err := conn.Read(make([]byte, 1)); err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil
+		}
+		return err
+	}
+	return nil
 }
 
 // RandomDelay 随机延迟
@@ -151,35 +180,34 @@ func FilterPorts(ports []int, excludePorts []int) []int {
 	return filtered
 }
 
+// defaultPorts 各协议的默认端口，由协议注册时写入
+var (
+	defaultPortsMutex sync.RWMutex
+	defaultPorts      = map[string][]int{}
+)
+
+// RegisterDefaultPorts 注册协议的默认端口，协议名不区分大小写
+func RegisterDefaultPorts(protocol string, ports []int) {
+	defaultPortsMutex.Lock()
+	defer defaultPortsMutex.Unlock()
+	defaultPorts[strings.ToLower(protocol)] = append([]int(nil), ports...)
+}
+
 // GetDefaultPorts 获取协议的默认端口
 func GetDefaultPorts(protocol string) []int {
-	defaults := map[string][]int{
-		"ssh":        {22},
-		"ftp":        {21},
-		"telnet":     {23},
-		"mysql":      {3306},
-		"postgresql": {5432},
-		"redis":      {6379},
-		"mongodb":    {27017},
-		"http":       {80, 8080, 8000, 8888},
-		"https":      {443, 8443},
-		"smb":        {445, 139},
-		"rdp":        {3389},
-		"vnc":        {5900, 5901, 5902},
-		"snmp":       {161},
-		"imap":       {143, 993},
-		"pop3":       {110, 995},
-		"smtp":       {25, 587, 465},
-	}
-
-	if ports, exists := defaults[strings.ToLower(protocol)]; exists {
-		return ports
+	defaultPortsMutex.RLock()
+	defer defaultPortsMutex.RUnlock()
+
+	if ports, exists := defaultPorts[strings.ToLower(protocol)]; exists {
+		return append([]int(nil), ports...)
 	}
 
 	return []int{}
 }
 
 // GenerateCommonUsernames 生成常见用户名
+//
+// Deprecated: 使用 packs.Credentials 按协议获取成对的默认凭据
 func GenerateCommonUsernames() []string {
 	return []string{
 		"admin", "administrator", "root", "user", "test", "guest", "oracle", "postgres",
@@ -191,6 +219,8 @@ func GenerateCommonUsernames() []string {
 }
 
 // GenerateCommonPasswords 生成常见密码
+//
+// Deprecated: 使用 packs.Credentials 按协议获取成对的默认凭据
 func GenerateCommonPasswords() []string {
 	return []string{
 		"123456", "password", "123456789", "12345678", "12345", "1234567890",
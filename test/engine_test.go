@@ -0,0 +1,1862 @@
+package brute_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/XTeam-Wing/x-crack/pkg/brute"
+	"github.com/XTeam-Wing/x-crack/pkg/packs"
+	"github.com/XTeam-Wing/x-crack/pkg/utils"
+	"github.com/samber/lo"
+)
+
+// newTestConfig 返回不依赖网络的快速测试配置
+func newTestConfig(callback brute.BruteCallback) *brute.Config {
+	config := brute.DefaultConfig()
+	config.MinDelay = 0
+	config.MaxDelay = 0
+	config.Timeout = time.Second
+	config.CustomCallback = callback
+	return config
+}
+
+func TestLazySourceWithDictFile(t *testing.T) {
+	dir := t.TempDir()
+	passFile := filepath.Join(dir, "pass.txt")
+	// 文件中已在内存字典中的 p0 只尝试一次
+	if err := os.WriteFile(passFile, []byte("# comment\np1\n\np2\np0\np3\n"), 0644); err != nil {
+		t.Fatalf("Failed to write dict: %v", err)
+	}
+
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		mu.Lock()
+		seen[item.Target+"/"+item.Username+"/"+item.Password]++
+		mu.Unlock()
+		return &brute.BruteResult{Item: item}
+	})
+	config.PassDictFile = passFile
+
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "10.0.0.1", 1).
+		WithTarget("test", "10.0.0.2", 1).
+		WithUserDict([]string{"root", "admin"}).
+		WithPassDict([]string{"p0"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	total, _, _, _, _, _ := engine.GetProgressStats()
+	if total != 16 {
+		t.Fatalf("Expected 16 total tasks, got %d", total)
+	}
+
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	if len(seen) != 16 {
+		t.Fatalf("Expected 16 distinct attempts, got %d", len(seen))
+	}
+	for key, count := range seen {
+		if count != 1 {
+			t.Fatalf("Expected %s to be attempted once, got %d", key, count)
+		}
+	}
+	if seen["10.0.0.2/admin/p3"] == 0 {
+		t.Fatal("Expected password from dict file to be attempted")
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	checkpointFile := filepath.Join(t.TempDir(), "checkpoint.json")
+	users := []string{"u1", "u2", "u3"}
+	passwords := []string{"p1", "p2", "p3", "p4", "p5"}
+
+	run := func(resume bool, stopAfter int) map[string]int {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var mu sync.Mutex
+		attempts := make(map[string]int)
+		config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+			mu.Lock()
+			attempts[item.Username+":"+item.Password]++
+			if len(attempts) == stopAfter {
+				cancel()
+			}
+			mu.Unlock()
+			return &brute.BruteResult{Item: item, Success: item.Password == "p5"}
+		})
+		config.TaskConcurrent = 2
+		config.CheckpointFile = checkpointFile
+
+		engine, err := brute.NewBuilder(ctx).
+			WithConfig(config).
+			WithTarget("test", "10.0.0.1", 1).
+			WithUserDict(users).
+			WithPassDict(passwords).
+			Build()
+		if err != nil {
+			t.Fatalf("Failed to build engine: %v", err)
+		}
+		if resume {
+			if err := engine.Resume(checkpointFile); err != nil {
+				t.Fatalf("Failed to resume: %v", err)
+			}
+		}
+		if err := engine.Start(); err != nil {
+			t.Fatalf("Failed to start engine: %v", err)
+		}
+		return attempts
+	}
+
+	first := run(false, 6)
+	second := run(true, -1)
+
+	for combo, count := range second {
+		if count != 1 || first[combo] != 0 {
+			t.Fatalf("Combination %s was retried after resume", combo)
+		}
+	}
+
+	checkpoint, err := brute.LoadCheckpoint(checkpointFile)
+	if err != nil {
+		t.Fatalf("Failed to load checkpoint: %v", err)
+	}
+	state := checkpoint.Targets["test:10.0.0.1:1"]
+	if state == nil || !state.Finished || len(state.Successes) != len(users) {
+		t.Fatalf("Unexpected final checkpoint state: %+v", state)
+	}
+
+	// 找到密码的用户的剩余组合会被跳过
+	if len(first)+len(second)+int(state.Skipped) != len(users)*len(passwords) {
+		t.Fatalf("Expected %d attempts across both runs, got %d+%d (skipped %d)",
+			len(users)*len(passwords), len(first), len(second), state.Skipped)
+	}
+}
+
+func TestRetryTransientFailures(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		mu.Lock()
+		calls[item.Password]++
+		count := calls[item.Password]
+		mu.Unlock()
+
+		result := &brute.BruteResult{Item: item}
+		switch {
+		case item.Password == "flaky" && count < 3:
+			result.Error = context.DeadlineExceeded
+		case item.Password == "flaky":
+			result.Success = true
+		default:
+			result.Error = errors.New("authentication failed")
+		}
+		return result
+	})
+	config.MaxRetries = 3
+	config.RetryBackoff = time.Millisecond
+
+	var results []*brute.BruteResult
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "10.0.0.1", 1).
+		WithUserDict([]string{"root"}).
+		WithPassDict([]string{"flaky", "wrong"}).
+		WithResultCallback(func(result *brute.BruteResult) {
+			mu.Lock()
+			results = append(results, result)
+			mu.Unlock()
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	if calls["wrong"] != 1 {
+		t.Fatalf("Auth rejection must not be retried, got %d calls", calls["wrong"])
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.Item.Password == "flaky" && (!result.Success || result.Attempts != 3) {
+			t.Fatalf("Expected flaky attempt to succeed on third try, got success=%v attempts=%d",
+				result.Success, result.Attempts)
+		}
+	}
+}
+
+func TestFailureKindClassification(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind brute.FailureKind
+	}{
+		{nil, brute.FailureNone},
+		{context.DeadlineExceeded, brute.FailureTimeout},
+		{context.Canceled, brute.FailureCanceled},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, brute.FailureConnRefused},
+		{&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, brute.FailureConnReset},
+		{&net.DNSError{Err: "no such host", Name: "invalid.example"}, brute.FailureUnreachable},
+		{errors.New("tls: handshake failure"), brute.FailureTLS},
+		{errors.New("account is locked"), brute.FailureLocked},
+		{errors.New("something odd"), brute.FailureUnknown},
+	}
+	for _, c := range cases {
+		if kind := brute.ClassifyError(c.err); kind != c.kind {
+			t.Errorf("ClassifyError(%v) = %s, expected %s", c.err, kind, c.kind)
+		}
+	}
+
+	if kind := brute.ClassifyAuthError(errors.New("WRONGPASS invalid password")); kind != brute.FailureAuthRejected {
+		t.Errorf("Expected auth error to be rejected, got %s", kind)
+	}
+
+	result := brute.NewResult(&brute.BruteItem{Type: "ssh"}).Fail(brute.FailureConnRefused, errors.New("connection refused"))
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Failed to marshal result: %v", err)
+	}
+	if !strings.Contains(string(data), `"failure_kind":"conn_refused"`) ||
+		!strings.Contains(string(data), `"error":"connection refused"`) {
+		t.Fatalf("Unexpected JSON output: %s", data)
+	}
+}
+
+func TestFailureStats(t *testing.T) {
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		result := brute.NewResult(item)
+		switch item.Password {
+		case "ok":
+			return result.Succeed("")
+		case "locked":
+			return result.Fail(brute.FailureLocked, errors.New("account locked"))
+		}
+		// 未显式标记的失败由引擎归类为认证被拒绝
+		return result
+	})
+
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "10.0.0.1", 1).
+		WithUserDict([]string{"root"}).
+		WithPassDict([]string{"ok", "locked", "wrong1", "wrong2"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	stats := engine.GetFailureStats()
+	if stats[brute.FailureAuthRejected] != 2 || stats[brute.FailureLocked] != 1 || stats[brute.FailureNone] != 0 {
+		t.Fatalf("Unexpected failure stats: %v", stats)
+	}
+}
+
+func TestPreCheckSkipsUnreachable(t *testing.T) {
+	open, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer open.Close()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	var mu sync.Mutex
+	attempted := make(map[int]int)
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		mu.Lock()
+		attempted[item.Port]++
+		mu.Unlock()
+		return brute.NewResult(item)
+	})
+	config.PreCheck = true
+	config.PreCheckTimeout = time.Second
+
+	openPort := open.Addr().(*net.TCPAddr).Port
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "127.0.0.1", openPort).
+		WithTarget("test", "127.0.0.1", closedPort).
+		WithUserDict([]string{"root"}).
+		WithPassDict([]string{"p1", "p2"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	if attempted[openPort] != 2 || attempted[closedPort] != 0 {
+		t.Fatalf("Unexpected attempts: %v", attempted)
+	}
+	abandoned := engine.GetAbandonedTargets()
+	reason, ok := abandoned[fmt.Sprintf("test:127.0.0.1:%d", closedPort)]
+	if len(abandoned) != 1 || !ok || !strings.Contains(reason, "conn_refused") {
+		t.Fatalf("Expected closed port to be reported as unreachable, got %v", abandoned)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	run := func(callback brute.BruteCallback, threshold int) (int, map[string]string) {
+		var mu sync.Mutex
+		attempts := 0
+		config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+			mu.Lock()
+			attempts++
+			mu.Unlock()
+			return callback(item)
+		})
+		config.TaskConcurrent = 1
+		config.MaxRetries = 0
+		config.FinishingThreshold = threshold
+
+		engine, err := brute.NewBuilder(context.Background()).
+			WithConfig(config).
+			WithTarget("test", "10.0.0.1", 1).
+			WithUserDict([]string{"root"}).
+			WithPassDict([]string{"p1", "p2", "p3", "p4", "p5", "p6", "p7", "p8", "p9", "p10"}).
+			Build()
+		if err != nil {
+			t.Fatalf("Failed to build engine: %v", err)
+		}
+		if err := engine.Start(); err != nil {
+			t.Fatalf("Failed to start engine: %v", err)
+		}
+		return attempts, engine.GetAbandonedTargets()
+	}
+
+	refused := func(item *brute.BruteItem) *brute.BruteResult {
+		return brute.NewResult(item).Fail(brute.FailureConnRefused, errors.New("connection refused"))
+	}
+	attempts, abandoned := run(refused, 3)
+	if attempts != 3 || !strings.Contains(abandoned["test:10.0.0.1:1"], "3 consecutive network failures") {
+		t.Fatalf("Expected target abandoned after 3 failures, got %d attempts, %v", attempts, abandoned)
+	}
+
+	// 阈值为 0 时不放弃目标
+	attempts, abandoned = run(refused, 0)
+	if attempts != 10 || len(abandoned) != 0 {
+		t.Fatalf("Expected all attempts without threshold, got %d attempts, %v", attempts, abandoned)
+	}
+
+	finished := func(item *brute.BruteItem) *brute.BruteResult {
+		result := brute.NewResult(item).Fail(brute.FailureLocked, errors.New("no pg_hba.conf entry"))
+		result.Finished = true
+		return result
+	}
+	attempts, abandoned = run(finished, 0)
+	if attempts != 1 || !strings.Contains(abandoned["test:10.0.0.1:1"], "handler reported finished") {
+		t.Fatalf("Expected target abandoned after handler finished, got %d attempts, %v", attempts, abandoned)
+	}
+}
+
+func TestUserElimination(t *testing.T) {
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		mu.Lock()
+		attempts[item.Username]++
+		mu.Unlock()
+
+		result := brute.NewResult(item)
+		switch {
+		case item.Username == "admin" && item.Password == "p2":
+			return result.Succeed("")
+		case item.Username == "ghost":
+			result.UserEliminated = true
+			return result.AuthRejected(errors.New("user does not exist"))
+		}
+		return result.AuthRejected(nil)
+	})
+	config.TaskConcurrent = 1
+
+	passwords := []string{"p1", "p2", "p3", "p4", "p5"}
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "10.0.0.1", 1).
+		WithUserDict([]string{"admin", "ghost", "root"}).
+		WithPassDict(passwords).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	if attempts["admin"] != 2 || attempts["ghost"] != 1 || attempts["root"] != len(passwords) {
+		t.Fatalf("Unexpected attempts per user: %v", attempts)
+	}
+
+	state := engine.Checkpoint().Targets["test:10.0.0.1:1"]
+	if state.Position != state.Total || state.Skipped != 7 || len(state.Eliminated) != 2 {
+		t.Fatalf("Unexpected checkpoint state: %+v", state)
+	}
+}
+
+func TestPasswordSpraying(t *testing.T) {
+	var mu sync.Mutex
+	var attempts []sprayAttempt
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		start := time.Now()
+		// 第二个目标较慢，其他目标需要等待它完成每一轮
+		if item.Target == "10.0.0.2" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		mu.Lock()
+		attempts = append(attempts, sprayAttempt{password: item.Password, start: start, end: time.Now()})
+		mu.Unlock()
+		return brute.NewResult(item).AuthRejected(nil)
+	})
+
+	window := 50 * time.Millisecond
+	passwords := []string{"p1", "p2", "p3"}
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithSpray(window, 1).
+		WithTargets([]brute.Target{
+			{Type: "test", Host: "10.0.0.1", Port: 1, Realm: "corp"},
+			{Type: "test", Host: "10.0.0.2", Port: 1, Realm: "corp"},
+		}).
+		WithUserDict([]string{"admin", "root", "guest"}).
+		WithPassDict(passwords).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	if len(attempts) != 18 {
+		t.Fatalf("Expected 18 attempts, got %d", len(attempts))
+	}
+	checkSprayRounds(t, attempts, passwords, window)
+}
+
+func TestPasswordSprayingQueuedTargets(t *testing.T) {
+	var mu sync.Mutex
+	var attempts []sprayAttempt
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		start := time.Now()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		attempts = append(attempts, sprayAttempt{password: item.Password, start: start, end: time.Now()})
+		mu.Unlock()
+		return brute.NewResult(item).AuthRejected(nil)
+	})
+
+	// 域内目标多于同时处理的目标数，排队的目标同样属于域，等待窗口的目标让出槽位
+	window := 50 * time.Millisecond
+	passwords := []string{"p1", "p2", "p3"}
+	var targets []brute.Target
+	for i := 1; i <= 4; i++ {
+		targets = append(targets, brute.Target{Type: "test", Host: fmt.Sprintf("10.0.0.%d", i), Port: 1, Realm: "corp"})
+	}
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithSpray(window, 1).
+		WithTargetOrder(brute.TargetOrderInput, 1).
+		WithTargets(targets).
+		WithUserDict([]string{"admin", "root"}).
+		WithPassDict(passwords).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- engine.Start() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Failed to start engine: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Spraying a realm larger than the active target limit did not finish")
+	}
+
+	if len(attempts) != 24 {
+		t.Fatalf("Expected 24 attempts, got %d", len(attempts))
+	}
+	checkSprayRounds(t, attempts, passwords, window)
+}
+
+// sprayAttempt 密码喷洒测试中的一次尝试
+type sprayAttempt struct {
+	password   string
+	start, end time.Time
+}
+
+// checkSprayRounds 检查每一轮在同一域内所有目标完成上一轮并度过观察窗口后才开始
+func checkSprayRounds(t *testing.T, attempts []sprayAttempt, passwords []string, window time.Duration) {
+	t.Helper()
+	for round := 1; round < len(passwords); round++ {
+		var lastEnd, firstStart time.Time
+		for _, a := range attempts {
+			switch a.password {
+			case passwords[round-1]:
+				if a.end.After(lastEnd) {
+					lastEnd = a.end
+				}
+			case passwords[round]:
+				if firstStart.IsZero() || a.start.Before(firstStart) {
+					firstStart = a.start
+				}
+			}
+		}
+		if gap := firstStart.Sub(lastEnd); gap < window {
+			t.Fatalf("Round %d started %v after the previous round, expected at least %v", round+1, gap, window)
+		}
+	}
+}
+
+func TestHostRateLimit(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		return brute.NewResult(item).AuthRejected(nil)
+	})
+	config.HostRateLimit = brute.RateLimit{Rate: 50, Burst: 1}
+
+	// 同一主机上的两个服务共享主机级别的速率
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("ssh", "10.0.0.1", 22).
+		WithTarget("ftp", "10.0.0.1", 21).
+		WithUserDict([]string{"admin"}).
+		WithPassDict([]string{"p1", "p2", "p3", "p4", "p5"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	if len(starts) != 10 {
+		t.Fatalf("Expected 10 attempts, got %d", len(starts))
+	}
+	first, last := starts[0], starts[0]
+	for _, start := range starts {
+		if start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+	}
+	// 50/s 且突发容量为 1 时，10 次请求至少需要 9 个间隔
+	if elapsed := last.Sub(first); elapsed < 160*time.Millisecond {
+		t.Fatalf("Host rate limit not applied, 10 attempts took %v", elapsed)
+	}
+}
+
+func TestRandomDelay(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		return brute.NewResult(item).AuthRejected(nil)
+	})
+	config.MinDelay = 20 * time.Millisecond
+	config.MaxDelay = 40 * time.Millisecond
+
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "10.0.0.1", 1).
+		WithUserDict([]string{"admin"}).
+		WithPassDict([]string{"p1", "p2", "p3", "p4", "p5"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	// 并发的工作协程仍需遵守同一目标相邻请求之间的延迟
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < 10*time.Millisecond {
+			t.Fatalf("Attempt %d started %v after the previous one, expected at least %v", i+1, gap, config.MinDelay)
+		}
+	}
+
+	if _, err := brute.ParseRateLimit("10/20"); err != nil {
+		t.Fatalf("Failed to parse rate limit: %v", err)
+	}
+	if _, err := brute.ParseRateLimit("-1"); err == nil {
+		t.Fatal("Expected negative rate to be rejected")
+	}
+}
+
+func TestDelayGlobalRate(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		return brute.NewResult(item).AuthRejected(nil)
+	})
+	config.TargetConcurrent = 4
+	config.MinDelay = 30 * time.Millisecond
+
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTargets([]brute.Target{
+			{Type: "test", Host: "10.0.0.1", Port: 1},
+			{Type: "test", Host: "10.0.0.2", Port: 1},
+			{Type: "test", Host: "10.0.0.3", Port: 1},
+			{Type: "test", Host: "10.0.0.4", Port: 1},
+		}).
+		WithUserDict([]string{"admin"}).
+		WithPassDict([]string{"p1", "p2"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	// 未设置全局限流时 MinDelay 同时是全局速率：突发 TargetConcurrent 个请求后每隔 MinDelay 一个，
+	// 而不是每个目标各自间隔 MinDelay
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	expected := time.Duration(len(starts)-config.TargetConcurrent-1) * config.MinDelay
+	if span := starts[len(starts)-1].Sub(starts[0]); span < expected {
+		t.Fatalf("Expected %d attempts to span at least %v at the global rate, got %v", len(starts), expected, span)
+	}
+}
+
+func TestRuntimeRateLimitLayers(t *testing.T) {
+	for _, layer := range brute.RateLayers() {
+		t.Run(string(layer), func(t *testing.T) {
+			var mu sync.Mutex
+			var starts []time.Time
+			config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+				mu.Lock()
+				starts = append(starts, time.Now())
+				mu.Unlock()
+				return brute.NewResult(item).AuthRejected(nil)
+			})
+
+			engine, err := brute.NewBuilder(context.Background()).
+				WithConfig(config).
+				WithTarget("test", "10.0.0.1", 1).
+				WithUserDict([]string{"admin"}).
+				WithPassDict([]string{"p1", "p2", "p3", "p4", "p5", "p6"}).
+				Build()
+			if err != nil {
+				t.Fatalf("Failed to build engine: %v", err)
+			}
+
+			// 启动时未启用的层也可以在运行时启用
+			engine.PauseScheduling()
+			if err := engine.Launch(); err != nil {
+				t.Fatalf("Failed to launch engine: %v", err)
+			}
+			limit := brute.RateLimit{Rate: 50, Burst: 1}
+			if err := engine.SetRateLimit(layer, limit); err != nil {
+				t.Fatalf("Failed to set %s rate limit: %v", layer, err)
+			}
+			engine.ResumeScheduling()
+			engine.Close()
+			if err := engine.Wait(); err != nil {
+				t.Fatalf("Failed to wait for engine: %v", err)
+			}
+
+			sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+			expected := time.Duration(len(starts)-2) * time.Second / time.Duration(limit.Rate)
+			if span := starts[len(starts)-1].Sub(starts[0]); len(starts) != 6 || span < expected {
+				t.Fatalf("Expected 6 attempts spanning at least %v, got %d over %v", expected, len(starts), span)
+			}
+		})
+	}
+
+	engine, err := brute.NewBuilder(context.Background()).WithConfig(newTestConfig(nil)).Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.SetRateLimit("datacenter", brute.RateLimit{Rate: 1}); err == nil {
+		t.Fatal("Expected unknown rate limit layer to be rejected")
+	}
+}
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	const targetKey = "test:10.0.0.1:1"
+	var engine *brute.Engine
+	var mu sync.Mutex
+	var calls int
+	minLimit := -1
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		mu.Lock()
+		calls++
+		call := calls
+		if limit := engine.GetTargetConcurrency()[targetKey].Limit; minLimit < 0 || limit < minLimit {
+			minLimit = limit
+		}
+		mu.Unlock()
+
+		result := brute.NewResult(item)
+		// 前 8 次尝试超时，之后目标恢复
+		if call <= 8 {
+			return result.Fail(brute.FailureTimeout, errors.New("i/o timeout"))
+		}
+		return result.AuthRejected(nil)
+	})
+	config.TaskConcurrent = 4
+	config.MaxRetries = 0
+	config.FinishingThreshold = 0
+	config.Adaptive = true
+	config.AdaptiveWindow = 4
+	config.AdaptiveMaxDelay = 20 * time.Millisecond
+
+	passwords := make([]string, 24)
+	for i := range passwords {
+		passwords[i] = fmt.Sprintf("p%d", i)
+	}
+	var err error
+	engine, err = brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "10.0.0.1", 1).
+		WithUserDict([]string{"admin"}).
+		WithPassDict(passwords).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	if minLimit > 2 {
+		t.Fatalf("Expected concurrency to back off to at most 2, lowest was %d", minLimit)
+	}
+	status := engine.GetTargetConcurrency()[targetKey]
+	if status.Limit != config.TaskConcurrent || status.Delay != 0 {
+		t.Fatalf("Expected concurrency to recover to %d without delay, got %+v", config.TaskConcurrent, status)
+	}
+	if _, globalTotal, _, targetTotal := engine.GetConcurrencyStatus(); globalTotal != config.TargetConcurrent || targetTotal != config.TaskConcurrent {
+		t.Fatalf("Unexpected concurrency status: global=%d target=%d", globalTotal, targetTotal)
+	}
+}
+
+func TestFeedRunningEngine(t *testing.T) {
+	var processed int64
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		mu.Lock()
+		seen[fmt.Sprintf("%s:%s", item.Target, item.Password)] = true
+		mu.Unlock()
+		atomic.AddInt64(&processed, 1)
+		return brute.NewResult(item).AuthRejected(nil)
+	})
+
+	engine, err := brute.NewEngine(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	if err := engine.AddTarget("test", "10.0.0.1", 1); err != nil {
+		t.Fatalf("Failed to add target: %v", err)
+	}
+	if err := engine.Launch(); err != nil {
+		t.Fatalf("Failed to launch engine: %v", err)
+	}
+
+	// 运行中从多个协程添加目标和任务，包括启动前已经添加的目标
+	var wg sync.WaitGroup
+	for i := 1; i <= 4; i++ {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			if err := engine.AddTarget("test", host, 1); err != nil {
+				t.Errorf("Failed to add target %s: %v", host, err)
+				return
+			}
+			for j := 0; j < 5; j++ {
+				time.Sleep(time.Millisecond)
+				item := &brute.BruteItem{Type: "test", Target: host, Port: 1, Username: "admin", Password: fmt.Sprintf("p%d", j)}
+				if err := engine.Feed(item); err != nil {
+					t.Errorf("Failed to feed %s: %v", host, err)
+				}
+			}
+		}(fmt.Sprintf("10.0.0.%d", i))
+	}
+	wg.Wait()
+
+	engine.Close()
+	if err := engine.Feed(&brute.BruteItem{Type: "test", Target: "10.0.0.1", Port: 1}); err == nil {
+		t.Fatal("Expected feed after close to fail")
+	}
+	if err := engine.Wait(); err != nil {
+		t.Fatalf("Failed to wait for engine: %v", err)
+	}
+
+	if processed != 20 || len(seen) != 20 {
+		t.Fatalf("Expected 20 distinct attempts, got %d attempts and %d distinct", processed, len(seen))
+	}
+	if engine.GetTargetCount() != 4 {
+		t.Fatalf("Expected 4 targets, got %d", engine.GetTargetCount())
+	}
+}
+
+func TestPauseAndResize(t *testing.T) {
+	var attempts, inFlight, maxInFlight int64
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		atomic.AddInt64(&attempts, 1)
+		current := atomic.AddInt64(&inFlight, 1)
+		for {
+			observed := atomic.LoadInt64(&maxInFlight)
+			if current <= observed || atomic.CompareAndSwapInt64(&maxInFlight, observed, current) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		atomic.AddInt64(&inFlight, -1)
+		return brute.NewResult(item).AuthRejected(nil)
+	})
+
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "10.0.0.1", 1).
+		WithTarget("test", "10.0.0.2", 1).
+		WithUserDict([]string{"admin", "root"}).
+		WithPassDict([]string{"p1", "p2", "p3", "p4", "p5"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	engine.PauseScheduling()
+	if err := engine.Launch(); err != nil {
+		t.Fatalf("Failed to launch engine: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt64(&attempts); n != 0 {
+		t.Fatalf("Expected no attempts while paused, got %d", n)
+	}
+
+	// 暂停期间收紧并发，恢复后生效
+	if err := engine.SetConcurrency(1, 1); err != nil {
+		t.Fatalf("Failed to set concurrency: %v", err)
+	}
+	if err := engine.SetConcurrency(0, 1); err == nil {
+		t.Fatal("Expected zero concurrency to be rejected")
+	}
+	if err := engine.SetDelay(time.Second, time.Millisecond); err == nil {
+		t.Fatal("Expected min delay above max delay to be rejected")
+	}
+	if _, globalTotal, _, targetTotal := engine.GetConcurrencyStatus(); globalTotal != 1 || targetTotal != 2 {
+		t.Fatalf("Unexpected concurrency status: global=%d target=%d", globalTotal, targetTotal)
+	}
+
+	engine.ResumeScheduling()
+	engine.Close()
+	if err := engine.Wait(); err != nil {
+		t.Fatalf("Failed to wait for engine: %v", err)
+	}
+
+	if attempts != 20 || maxInFlight != 1 {
+		t.Fatalf("Expected 20 serial attempts, got %d attempts with %d in flight", attempts, maxInFlight)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		result := brute.NewResult(item)
+		switch {
+		case item.Target == "10.0.0.2":
+			return result.Fail(brute.FailureConnRefused, errors.New("connection refused"))
+		case item.Password == "p2":
+			return result.Succeed("")
+		}
+		return result.AuthRejected(nil)
+	})
+	config.TaskConcurrent = 1
+	config.FinishingThreshold = 2
+
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "10.0.0.1", 1).
+		WithTarget("test", "10.0.0.2", 1).
+		WithUserDict([]string{"admin"}).
+		WithPassDict([]string{"p1", "p2", "p3"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	// 无缓冲的慢速订阅者不会丢失事件
+	all := engine.Subscribe(0)
+	found := engine.Subscribe(0, brute.EventCredentialFound)
+
+	counts := make(map[brute.EventType]int)
+	var events []brute.Event
+	var credentials []brute.Event
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for event := range all.Events() {
+			time.Sleep(time.Millisecond)
+			counts[event.Type]++
+			events = append(events, event)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for event := range found.Events() {
+			credentials = append(credentials, event)
+		}
+	}()
+
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	wg.Wait()
+
+	if counts[brute.EventTargetStarted] != 2 || counts[brute.EventTargetFinished] != 2 ||
+		counts[brute.EventAttemptCompleted] != 4 || counts[brute.EventCredentialFound] != 1 ||
+		counts[brute.EventTargetSkipped] != 1 || counts[brute.EventEngineDone] != 1 {
+		t.Fatalf("Unexpected event counts: %v", counts)
+	}
+	if last := events[len(events)-1]; last.Type != brute.EventEngineDone {
+		t.Fatalf("Expected EngineDone to be the last event, got %s", last.Type)
+	}
+	for _, event := range events {
+		if event.Type == brute.EventTargetSkipped && (event.Target != "test:10.0.0.2:1" || event.Reason == "") {
+			t.Fatalf("Unexpected skip event: %+v", event)
+		}
+	}
+	if len(credentials) != 1 || credentials[0].Result.Item.Password != "p2" {
+		t.Fatalf("Unexpected credential events: %+v", credentials)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		result := brute.NewResult(item)
+		if item.Password == "p2" {
+			return result.Succeed("")
+		}
+		return result.AuthRejected(nil)
+	})
+
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("ssh", "10.0.0.1", 22).
+		WithTarget("tëst\"\\", "10.0.0.2", 22).
+		WithUserDict([]string{"admin", "root"}).
+		WithPassDict([]string{"p1", "p2"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	engine.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+
+	for _, expected := range []string{
+		"# TYPE xcrack_attempts_total counter",
+		`xcrack_tasks_processed_total 8`,
+		`xcrack_attempts_total{protocol="ssh",result="success",failure_kind="none"} 2`,
+		`xcrack_attempts_total{protocol="ssh",result="failure",failure_kind="auth_rejected"} 2`,
+		`xcrack_attempt_duration_seconds_bucket{protocol="ssh",le="+Inf"} 4`,
+		`xcrack_attempt_duration_seconds_count{protocol="ssh"} 4`,
+		`xcrack_concurrency_limit{scope="global"} 10`,
+		// 标签值只转义反斜杠、双引号和换行，非 ASCII 字符原样输出
+		`xcrack_attempts_total{protocol="tëst\"\\",result="success",failure_kind="none"} 2`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("Metrics output missing %q:\n%s", expected, body)
+		}
+	}
+}
+
+func TestTargetStats(t *testing.T) {
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		time.Sleep(5 * time.Millisecond)
+		result := brute.NewResult(item)
+		switch {
+		case item.Target == "10.0.0.1":
+			return result.Fail(brute.FailureConnRefused, errors.New("connection refused"))
+		case item.Password == "p4":
+			return result.Fail(brute.FailureTimeout, errors.New("i/o timeout"))
+		default:
+			return result.AuthRejected(nil)
+		}
+	})
+	config.TaskConcurrent = 1
+	config.MaxRetries = 0
+	config.FinishingThreshold = 3
+
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "10.0.0.1", 1).
+		WithTarget("test", "10.0.0.2", 1).
+		WithUserDict([]string{"root"}).
+		WithPassDict([]string{"p1", "p2", "p3", "p4", "p5", "p6", "p7", "p8", "p9", "p10"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	stats := engine.GetTargetStats()
+	if len(stats) != 2 || stats[0].Target != "test:10.0.0.1:1" || stats[1].Target != "test:10.0.0.2:1" {
+		t.Fatalf("Expected stats for both targets in insertion order, got %+v", stats)
+	}
+
+	abandoned := stats[0]
+	if abandoned.Attempts != 3 || abandoned.Failures[brute.FailureConnRefused] != 3 || abandoned.Skipped != 7 || abandoned.Abandoned == "" {
+		t.Fatalf("Unexpected stats for abandoned target: %+v", abandoned)
+	}
+
+	finished := stats[1]
+	if finished.Attempts != 10 || finished.Failed() != 10 ||
+		finished.Failures[brute.FailureAuthRejected] != 9 || finished.Failures[brute.FailureTimeout] != 1 {
+		t.Fatalf("Unexpected failure counts: %+v", finished)
+	}
+	if finished.AvgLatency < 5*time.Millisecond || finished.P95Latency < finished.AvgLatency/2 {
+		t.Fatalf("Unexpected latency: avg=%v p95=%v", finished.AvgLatency, finished.P95Latency)
+	}
+	if finished.FirstAttempt.IsZero() || !finished.LastAttempt.After(finished.FirstAttempt) {
+		t.Fatalf("Unexpected attempt times: %v - %v", finished.FirstAttempt, finished.LastAttempt)
+	}
+
+	// 失败类型以名称作为 JSON 键
+	data, err := json.Marshal(finished)
+	if err != nil || !strings.Contains(string(data), `"auth_rejected":9`) {
+		t.Fatalf("Unexpected JSON: %s, %v", data, err)
+	}
+}
+
+func TestTargetScheduling(t *testing.T) {
+	hosts := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.1.1", "10.0.1.2", "10.0.2.1"}
+	run := func(order brute.TargetOrder, seed int64) ([]string, int32) {
+		var mu sync.Mutex
+		var started []string
+		var active, peak int32
+		config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+			current := atomic.AddInt32(&active, 1)
+			defer atomic.AddInt32(&active, -1)
+			for {
+				old := atomic.LoadInt32(&peak)
+				if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+					break
+				}
+			}
+			mu.Lock()
+			if len(started) == 0 || started[len(started)-1] != item.Target {
+				started = append(started, item.Target)
+			}
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			return brute.NewResult(item).AuthRejected(nil)
+		})
+		config.TargetConcurrent = 10
+		config.TaskConcurrent = 1
+		config.ShuffleSeed = seed
+
+		builder := brute.NewBuilder(context.Background()).
+			WithConfig(config).
+			WithTargetOrder(order, 1).
+			WithUserDict([]string{"root"}).
+			WithPassDict([]string{"p1", "p2"})
+		for _, host := range hosts {
+			builder.WithTarget("test", host, 1)
+		}
+		engine, err := builder.Build()
+		if err != nil {
+			t.Fatalf("Failed to build engine: %v", err)
+		}
+		if err := engine.Start(); err != nil {
+			t.Fatalf("Failed to start engine: %v", err)
+		}
+		return started, atomic.LoadInt32(&peak)
+	}
+
+	// 同时只处理一个目标，目标按添加顺序依次完成
+	started, peak := run(brute.TargetOrderInput, 0)
+	if peak != 1 || strings.Join(started, ",") != strings.Join(hosts, ",") {
+		t.Fatalf("Expected targets one at a time in input order, got %v (peak %d)", started, peak)
+	}
+
+	started, _ = run(brute.TargetOrderSubnet, 0)
+	expected := "10.0.0.1,10.0.1.1,10.0.2.1,10.0.0.2,10.0.1.2,10.0.0.3"
+	if strings.Join(started, ",") != expected {
+		t.Fatalf("Expected subnets interleaved as %s, got %v", expected, started)
+	}
+
+	// 相同的种子得到相同的随机顺序
+	first, _ := run(brute.TargetOrderShuffle, 42)
+	second, _ := run(brute.TargetOrderShuffle, 42)
+	if len(first) != len(hosts) || strings.Join(first, ",") != strings.Join(second, ",") {
+		t.Fatalf("Expected reproducible shuffle, got %v and %v", first, second)
+	}
+}
+
+func TestAuthModels(t *testing.T) {
+	noop := func(item *brute.BruteItem) *brute.BruteResult {
+		return brute.NewResult(item).AuthRejected(nil)
+	}
+	for name, model := range map[string]brute.AuthModel{
+		"test-password-only":     brute.AuthPasswordOnly,
+		"test-username-optional": brute.AuthUsernameOptional,
+	} {
+		if err := brute.RegisterProtocol(brute.NewProtocolHandler(brute.ProtocolInfo{Name: name, AuthModel: model}, noop)); err != nil {
+			t.Fatalf("Failed to register %s: %v", name, err)
+		}
+	}
+
+	run := func(protocol string, onlyNeedPassword bool) (int64, []string) {
+		var mu sync.Mutex
+		var attempts []string
+		config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+			mu.Lock()
+			attempts = append(attempts, item.Username+":"+item.Password)
+			mu.Unlock()
+			return noop(item)
+		})
+		config.TaskConcurrent = 1
+		config.OnlyNeedPassword = onlyNeedPassword
+
+		engine, err := brute.NewBuilder(context.Background()).
+			WithConfig(config).
+			WithTarget(protocol, "10.0.0.1", 1).
+			WithUserDict([]string{"admin", "", "root"}).
+			WithPassDict([]string{"p1", "p2"}).
+			Build()
+		if err != nil {
+			t.Fatalf("Failed to build engine: %v", err)
+		}
+		total, _, _, _, _, _ := engine.GetProgressStats()
+		if err := engine.Start(); err != nil {
+			t.Fatalf("Failed to start engine: %v", err)
+		}
+		return total, attempts
+	}
+
+	// 只需要密码的协议每个密码只尝试一次，进度总数与实际尝试一致
+	total, attempts := run("test-password-only", false)
+	if total != 2 || strings.Join(attempts, ",") != ":p1,:p2" {
+		t.Fatalf("Expected one attempt per password, got total %d: %v", total, attempts)
+	}
+
+	// 用户名可为空的协议额外尝试一次空用户名
+	total, attempts = run("test-username-optional", false)
+	if total != 6 || strings.Join(attempts, ",") != ":p1,:p2,admin:p1,admin:p2,root:p1,root:p2" {
+		t.Fatalf("Expected blank username first, got total %d: %v", total, attempts)
+	}
+
+	total, attempts = run("ssh", false)
+	if total != 4 {
+		t.Fatalf("Expected usernames x passwords, got total %d: %v", total, attempts)
+	}
+	total, _ = run("ssh", true)
+	if total != 2 {
+		t.Fatalf("Expected OnlyNeedPassword to ignore usernames, got total %d", total)
+	}
+}
+
+func TestProtocolRegistry(t *testing.T) {
+	noop := func(item *brute.BruteItem) *brute.BruteResult {
+		return brute.NewResult(item).AuthRejected(nil)
+	}
+	err := brute.RegisterProtocol(brute.NewProtocolHandler(brute.ProtocolInfo{
+		Name:         "test-registry",
+		Aliases:      []string{"Test-Alias"},
+		DefaultPorts: []int{1234},
+		Transport:    brute.TransportUDP,
+		AuthModel:    brute.AuthPasswordOnly,
+	}, noop))
+	if err != nil {
+		t.Fatalf("Failed to register protocol: %v", err)
+	}
+
+	// 别名不区分大小写，解析为协议名
+	if name, ok := brute.ResolveProtocol("test-alias"); !ok || name != "test-registry" {
+		t.Fatalf("Expected alias to resolve to test-registry, got %q %v", name, ok)
+	}
+	if ports := brute.GetDefaultPorts("TEST-ALIAS"); len(ports) != 1 || ports[0] != 1234 {
+		t.Fatalf("Expected default port 1234 through alias, got %v", ports)
+	}
+	if model := brute.GetAuthModel("test-alias"); model != brute.AuthPasswordOnly {
+		t.Fatalf("Expected password_only through alias, got %v", model)
+	}
+	if _, ok := brute.GetProtocolHandler("test-alias"); !ok {
+		t.Fatal("Expected handler through alias")
+	}
+
+	// 兼容接口注册的协议需要用户名和密码
+	brute.RegisterProtocolHandler("test-legacy", noop)
+	protocols := brute.GetProtocols()
+	i := slices.IndexFunc(protocols, func(info brute.ProtocolInfo) bool { return info.Name == "test-legacy" })
+	if i < 0 || protocols[i].AuthModel != brute.AuthUserPassword || protocols[i].Transport != brute.TransportTCP {
+		t.Fatalf("Expected legacy protocol with user_password over tcp, got %+v", protocols)
+	}
+	if supported := brute.GetSupportedProtocols(); !slices.Contains(supported, "test-registry") || slices.Contains(supported, "test-alias") {
+		t.Fatalf("Expected supported protocols to list names only, got %v", supported)
+	}
+
+	if err := brute.RegisterProtocol(brute.NewProtocolHandler(brute.ProtocolInfo{Name: "test-bad", Transport: "sctp"}, noop)); err == nil {
+		t.Fatal("Expected unknown transport to be rejected")
+	}
+}
+
+func TestStopClosesConnections(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	// 服务端接受连接后不响应，记录每个连接何时被客户端关闭
+	accepted := make(chan struct{}, 16)
+	closed := make(chan struct{}, 16)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- struct{}{}
+			go func() {
+				defer conn.Close()
+				conn.Read(make([]byte, 1))
+				closed <- struct{}{}
+			}()
+		}
+	}()
+
+	var kinds sync.Map
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		result := brute.NewResult(item)
+		ctx, cancel := item.TimeoutContext()
+		defer cancel()
+		conn, err := utils.DialContext(ctx, "tcp", listener.Addr().String(), item.Timeout)
+		if err != nil {
+			return result.FailWithError(err)
+		}
+		defer conn.Close()
+		_, err = conn.Read(make([]byte, 1))
+		result.FailWithError(err)
+		kinds.Store(result.FailureKind, true)
+		return result
+	})
+	config.Timeout = time.Minute
+	config.TaskConcurrent = 2
+
+	var recorded int64
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "10.0.0.1", 1).
+		WithUserDict([]string{"admin"}).
+		WithPassDict([]string{"p1", "p2", "p3"}).
+		WithResultCallback(func(result *brute.BruteResult) {
+			atomic.AddInt64(&recorded, 1)
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Launch(); err != nil {
+		t.Fatalf("Failed to launch engine: %v", err)
+	}
+	for range 2 {
+		select {
+		case <-accepted:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected handlers to connect")
+		}
+	}
+
+	// 停止引擎后阻塞在读上的连接立即关闭，而不是等到一分钟的超时
+	stopped := make(chan struct{})
+	go func() {
+		engine.Stop()
+		close(stopped)
+	}()
+	for range 2 {
+		select {
+		case <-closed:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected connections to be closed after Stop")
+		}
+	}
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Stop to return promptly")
+	}
+
+	if _, ok := kinds.Load(brute.FailureCanceled); !ok {
+		t.Fatal("Expected reads interrupted by Stop to be classified as canceled")
+	}
+	if n := atomic.LoadInt64(&recorded); n != 0 {
+		t.Fatalf("Expected canceled attempts not to be recorded, got %d", n)
+	}
+}
+
+// fakeSession 记录尝试次数的会话，第 stale 次尝试时模拟服务端已关闭空闲连接
+// 设置 username 时与 SSH 会话一样只能尝试该用户名
+type fakeSession struct {
+	attempts int
+	stale    int
+	username string
+	closed   *int64
+}
+
+func (s *fakeSession) Accepts(item *brute.BruteItem) bool {
+	return s.username == "" || s.username == item.Username
+}
+
+func (s *fakeSession) Authenticate(item *brute.BruteItem) (*brute.BruteResult, bool) {
+	s.attempts++
+	result := brute.NewResult(item)
+	if s.attempts == s.stale {
+		return result.Fail(brute.FailureConnReset, syscall.ECONNRESET), false
+	}
+	if item.Password == "secret" {
+		return result.Succeed("ok"), false
+	}
+	return result.AuthRejected(nil), true
+}
+
+func (s *fakeSession) Close() error {
+	atomic.AddInt64(s.closed, 1)
+	return nil
+}
+
+func TestSessionReuse(t *testing.T) {
+	var closed int64
+	var mu sync.Mutex
+	tried := make(map[string]int)
+	handler := brute.NewSessionProtocolHandler(brute.ProtocolInfo{Name: "test-session"},
+		func(item *brute.BruteItem) *brute.BruteResult {
+			t.Error("Per-attempt callback must not be used with session reuse")
+			return brute.NewResult(item).AuthRejected(nil)
+		},
+		func(ctx context.Context, item *brute.BruteItem) (brute.Session, error) {
+			return &fakeSession{stale: 4, closed: &closed}, nil
+		})
+	if err := brute.RegisterProtocol(handler); err != nil {
+		t.Fatalf("Failed to register protocol: %v", err)
+	}
+
+	config := newTestConfig(nil)
+	config.TaskConcurrent = 1
+	config.SessionReuse = []string{"test-session"}
+
+	passwords := []string{"p1", "p2", "p3", "p4", "p5", "p6", "p7", "secret"}
+	var found int64
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test-session", "10.0.0.1", 1).
+		WithUserDict([]string{"root"}).
+		WithPassDict(passwords).
+		WithResultCallback(func(result *brute.BruteResult) {
+			mu.Lock()
+			tried[result.Item.Password]++
+			mu.Unlock()
+			if result.Success {
+				atomic.AddInt64(&found, 1)
+			}
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	// 每组凭据只记录一次结果，空闲会话失效时换用新会话而不是记为失败
+	for _, password := range passwords {
+		if tried[password] != 1 {
+			t.Fatalf("Expected %s to be recorded once, got %d", password, tried[password])
+		}
+	}
+	if found != 1 {
+		t.Fatalf("Expected 1 credential found, got %d", found)
+	}
+	opened, reused := engine.GetSessionStats()
+	if opened != 3 || reused != 5 {
+		t.Fatalf("Expected 3 sessions opened and 5 reused attempts, got %d and %d", opened, reused)
+	}
+	if closed != opened {
+		t.Fatalf("Expected all %d sessions to be closed, got %d", opened, closed)
+	}
+
+	// 不支持会话复用的协议不能启用
+	config.SessionReuse = []string{"test-registry-nosession"}
+	brute.RegisterProtocolHandler("test-registry-nosession", handler.Authenticate)
+	if _, err := brute.NewBuilder(context.Background()).WithConfig(config).Build(); err == nil {
+		t.Fatal("Expected session reuse on an unsupported protocol to be rejected")
+	}
+}
+
+func TestSessionPoolBounded(t *testing.T) {
+	var opened, closed, peak int64
+	handler := brute.NewSessionProtocolHandler(brute.ProtocolInfo{Name: "test-session-user"},
+		func(item *brute.BruteItem) *brute.BruteResult {
+			return brute.NewResult(item).AuthRejected(nil)
+		},
+		func(ctx context.Context, item *brute.BruteItem) (brute.Session, error) {
+			open := atomic.AddInt64(&opened, 1) - atomic.LoadInt64(&closed)
+			for {
+				current := atomic.LoadInt64(&peak)
+				if open <= current || atomic.CompareAndSwapInt64(&peak, current, open) {
+					break
+				}
+			}
+			return &fakeSession{username: item.Username, closed: &closed}, nil
+		})
+	if err := brute.RegisterProtocol(handler); err != nil {
+		t.Fatalf("Failed to register protocol: %v", err)
+	}
+
+	config := newTestConfig(nil)
+	config.TaskConcurrent = 3
+	config.SessionReuse = []string{"test-session-user"}
+	users := make([]string, 10)
+	for i := range users {
+		users[i] = fmt.Sprintf("user%d", i)
+	}
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test-session-user", "10.0.0.1", 1).
+		WithUserDict(users).
+		WithPassDict([]string{"p1", "p2", "p3", "p4", "p5", "p6"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	// 转到下一个用户名后之前用户名的空闲会话被关闭，同时打开的会话不超过进行中和空闲上限之和
+	if limit := int64(2 * config.TaskConcurrent); peak > limit {
+		t.Fatalf("Expected at most %d open sessions, got %d", limit, peak)
+	}
+	if closed != opened {
+		t.Fatalf("Expected all %d sessions to be closed, got %d", opened, closed)
+	}
+}
+
+func TestEnrichResults(t *testing.T) {
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		result := brute.NewResult(item)
+		if item.Password != "secret" {
+			return result.AuthRejected(nil)
+		}
+		result.Succeed("ok")
+		if item.Enrich {
+			result.SetExtra("version", "1.0")
+		}
+		return result
+	})
+	config.Enrich = true
+
+	var mu sync.Mutex
+	var found []*brute.BruteResult
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "10.0.0.1", 1).
+		WithUserDict([]string{"root"}).
+		WithPassDict([]string{"wrong", "secret"}).
+		WithResultCallback(func(result *brute.BruteResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			if result.Success {
+				found = append(found, result)
+			} else if result.ExtraInfo != nil {
+				t.Errorf("Expected no extra info on failed attempt, got %v", result.ExtraInfo)
+			}
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	if len(found) != 1 || found[0].ExtraInfo["version"] != "1.0" {
+		t.Fatalf("Expected enriched success, got %+v", found)
+	}
+	data, err := json.Marshal(found[0])
+	if err != nil || !strings.Contains(string(data), `"extra_info":{"version":"1.0"}`) {
+		t.Fatalf("Expected extra_info in json output, got %s (%v)", data, err)
+	}
+}
+
+func TestAcceptAnyDetection(t *testing.T) {
+	passwords := []string{"p1", "p2", "p3", "p4", "p5", "p6", "p7", "p8", "secret"}
+	var probes int64
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		result := brute.NewResult(item)
+		if !slices.Contains(passwords, item.Password) {
+			atomic.AddInt64(&probes, 1)
+		}
+		// honeypot 接受任意密码，genuine 只接受 secret
+		if item.Target == "honeypot" || item.Password == "secret" {
+			return result.Succeed("ok")
+		}
+		return result.AuthRejected(nil)
+	})
+	config.VerifySuccess = true
+	config.TaskConcurrent = 4
+
+	var mu sync.Mutex
+	found := make(map[string]int)
+	acceptAny := make(map[string]int)
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "honeypot", 1).
+		WithTarget("test", "genuine", 1).
+		WithUserDict([]string{"root"}).
+		WithPassDict(passwords).
+		WithResultCallback(func(result *brute.BruteResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case result.Success:
+				found[result.Item.Target]++
+			case result.FailureKind == brute.FailureAcceptAny:
+				acceptAny[result.Item.Target]++
+			}
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	// 每个目标只用随机密码验证一次
+	if probes != 2 {
+		t.Fatalf("Expected one random password probe per target, got %d", probes)
+	}
+	if found["honeypot"] != 0 || acceptAny["honeypot"] == 0 {
+		t.Fatalf("Expected honeypot successes to be flagged, got %d found and %d accept_any", found["honeypot"], acceptAny["honeypot"])
+	}
+	if found["genuine"] != 1 || acceptAny["genuine"] != 0 {
+		t.Fatalf("Expected 1 genuine credential, got %d found and %d accept_any", found["genuine"], acceptAny["genuine"])
+	}
+	if reason := engine.GetAbandonedTargets()["test:honeypot:1"]; !strings.Contains(reason, "random password") {
+		t.Fatalf("Expected honeypot to be abandoned, got %q", reason)
+	}
+}
+
+func TestUnauthProbe(t *testing.T) {
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	authenticate := func(item *brute.BruteItem) *brute.BruteResult {
+		mu.Lock()
+		attempts[item.Target]++
+		mu.Unlock()
+		return brute.NewResult(item).AuthRejected(nil)
+	}
+	probe := func(item *brute.BruteItem) *brute.BruteResult {
+		result := brute.NewResult(item)
+		if item.Target == "open" {
+			return result.Succeed("no auth")
+		}
+		return result.AuthRejected(nil)
+	}
+	if err := brute.RegisterProtocol(brute.WithUnauthProbe(brute.NewProtocolHandler(brute.ProtocolInfo{Name: "test-unauth"}, authenticate), probe)); err != nil {
+		t.Fatalf("Failed to register protocol: %v", err)
+	}
+	if !brute.SupportsUnauthProbe("test-unauth") {
+		t.Fatal("Expected test-unauth to support unauthenticated probes")
+	}
+
+	config := newTestConfig(nil)
+	config.UnauthProbe = true
+
+	var unauth []*brute.BruteResult
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test-unauth", "open", 1).
+		WithTarget("test-unauth", "closed", 1).
+		WithUserDict([]string{"root"}).
+		WithPassDict([]string{"p1", "p2"}).
+		WithResultCallback(func(result *brute.BruteResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			if result.Unauthenticated {
+				unauth = append(unauth, result)
+			}
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	// 开放的目标只报告未授权访问，不再尝试凭据
+	if len(unauth) != 1 || unauth[0].Item.Target != "open" || !unauth[0].Success {
+		t.Fatalf("Expected one unauthenticated finding for open, got %v", unauth)
+	}
+	if !strings.HasPrefix(unauth[0].String(), "[UNAUTH] test-unauth://open:1") {
+		t.Fatalf("Unexpected unauthenticated result string: %s", unauth[0])
+	}
+	if attempts["open"] != 0 || attempts["closed"] != 2 {
+		t.Fatalf("Expected credentials only against closed, got %v", attempts)
+	}
+	if engine.GetUnauthTargets() != 1 || len(engine.GetAbandonedTargets()) != 0 {
+		t.Fatalf("Expected 1 unauthenticated and no abandoned targets, got %d and %v", engine.GetUnauthTargets(), engine.GetAbandonedTargets())
+	}
+	for _, stats := range engine.GetTargetStats() {
+		if stats.Unauthenticated != (stats.Host == "open") {
+			t.Fatalf("Unexpected unauthenticated flag in stats: %+v", stats)
+		}
+	}
+}
+
+func TestCredentialPacks(t *testing.T) {
+	var mu sync.Mutex
+	var attempts []string
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		mu.Lock()
+		attempts = append(attempts, item.Username+":"+item.Password)
+		mu.Unlock()
+		return brute.NewResult(item).AuthRejected(nil)
+	})
+
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "10.0.0.1", 1).
+		WithUserDict([]string{"root", "admin"}).
+		WithPassDict([]string{"toor", "admin"}).
+		WithCredentialProvider(func(protocol string) []brute.Credential {
+			return packs.Credentials("amqp")
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	// 凭据成对尝试，不与字典或彼此交叉组合
+	expected := []string{"guest:guest", "admin:admin", "rabbitmq:rabbitmq"}
+	sort.Strings(attempts)
+	sort.Strings(expected)
+	if !slices.Equal(attempts, expected) {
+		t.Fatalf("Expected attempts %v, got %v", expected, attempts)
+	}
+
+	// 默认跳过空用户名和空密码，凭据包中的空字段仍然逐组尝试
+	busybox, _ := packs.Get("busybox-telnet")
+	cisco, _ := packs.Get("cisco-telnet")
+	selected := packs.Merge([]*packs.Pack{busybox, cisco})
+	attempts = nil
+	engine, err = brute.NewBuilder(context.Background()).
+		WithConfig(newTestConfig(config.CustomCallback)).
+		WithTarget("test", "10.0.0.2", 1).
+		WithCredentialProvider(func(protocol string) []brute.Credential {
+			return selected
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	expected = lo.Map(selected, func(credential brute.Credential, _ int) string {
+		return credential.Username + ":" + credential.Password
+	})
+	sort.Strings(attempts)
+	sort.Strings(expected)
+	if !slices.Equal(attempts, expected) || !slices.Contains(attempts, "root:") || !slices.Contains(attempts, ":cisco") {
+		t.Fatalf("Expected every pack entry %v, got %v", expected, attempts)
+	}
+
+	if pack, ok := packs.Get("oracle"); !ok || !slices.Contains(pack.Credentials, brute.Credential{Username: "scott", Password: "tiger"}) {
+		t.Fatalf("Expected oracle pack with scott:tiger, got %+v", pack)
+	}
+	if len(packs.Credentials("unknown")) != 0 {
+		t.Fatal("Expected no credentials for unknown protocol")
+	}
+}
+
+func TestPasswordRules(t *testing.T) {
+	cases := []struct {
+		rule, word, expected string
+	}{
+		{"c $@ $2 $0 $2 $4", "admin", "Admin@2024"},
+		{"c sa@ so0 $!", "password", "P@ssw0rd!"},
+		{"u", "acme", "ACME"},
+		{"^! r", "abc", "cba!"},
+		{"d", "ab", "abab"},
+		{"t T0", "aBc", "abC"},
+		{"[ ] '3", "xhello", "hel"},
+		{"@l D0", "hello", "eo"},
+		// 多字节的字符参数按一个字符处理
+		{"$é ^ü", "cafe", "ücafeé"},
+		{"sàa @ö", "vöilà", "vila"},
+	}
+	for _, c := range cases {
+		rule, err := brute.ParseRule(c.rule)
+		if err != nil {
+			t.Fatalf("Failed to parse rule %q: %v", c.rule, err)
+		}
+		if got := rule.Apply(c.word); got != c.expected {
+			t.Errorf("Rule %q on %q: expected %q, got %q", c.rule, c.word, c.expected, got)
+		}
+	}
+	if _, err := brute.ParseRule("$"); err == nil {
+		t.Error("Expected error for rule missing its argument")
+	}
+	if _, err := brute.ParseRule("X"); err == nil {
+		t.Error("Expected error for unsupported rule operation")
+	}
+
+	rulesFile := filepath.Join(t.TempDir(), "rules.txt")
+	if err := os.WriteFile(rulesFile, []byte("# keep the word\n:\nc\n$1\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write rules: %v", err)
+	}
+
+	var mu sync.Mutex
+	var attempts []string
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		mu.Lock()
+		attempts = append(attempts, item.Password)
+		mu.Unlock()
+		return brute.NewResult(item).AuthRejected(nil)
+	})
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "10.0.0.1", 1).
+		WithUserDict([]string{"root"}).
+		WithPassDict([]string{"acme", "Acme"}).
+		WithRulesFile(rulesFile).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	total, _, _, _, _, _ := engine.GetProgressStats()
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	// 每个词只输出不重复的变形，Acme 的 : 与 c 相同
+	expected := []string{"acme", "Acme", "acme1", "Acme", "Acme1"}
+	sort.Strings(attempts)
+	sort.Strings(expected)
+	if !slices.Equal(attempts, expected) || total != int64(len(expected)) {
+		t.Fatalf("Expected %d attempts %v, got total %d attempts %v", len(expected), expected, total, attempts)
+	}
+}
+
+func TestVerifyRetriesProbe(t *testing.T) {
+	var mu sync.Mutex
+	probes := make(map[string]int)
+	config := newTestConfig(func(item *brute.BruteItem) *brute.BruteResult {
+		result := brute.NewResult(item)
+		if item.Password == "secret" {
+			return result.Succeed("ok")
+		}
+		// 两个目标都接受任意密码，随机密码验证时 flaky 前两次超时，down 一直超时
+		mu.Lock()
+		probes[item.Target]++
+		count := probes[item.Target]
+		mu.Unlock()
+		if item.Target == "down" || count <= 2 {
+			return result.Fail(brute.FailureTimeout, errors.New("i/o timeout"))
+		}
+		return result.Succeed("ok")
+	})
+	config.VerifySuccess = true
+	config.RetryBackoff = time.Millisecond
+	config.FinishingThreshold = 0
+
+	var found []*brute.BruteResult
+	engine, err := brute.NewBuilder(context.Background()).
+		WithConfig(config).
+		WithTarget("test", "flaky", 1).
+		WithTarget("test", "down", 1).
+		WithUserDict([]string{"root"}).
+		WithPassDict([]string{"secret"}).
+		WithResultCallback(func(result *brute.BruteResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			found = append(found, result)
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	// 验证超时时按重试次数重试，没有结论时仍报告凭据并标记为未验证
+	results := make(map[string]*brute.BruteResult)
+	for _, result := range found {
+		results[result.Item.Target] = result
+	}
+	if flaky := results["flaky"]; flaky == nil || flaky.FailureKind != brute.FailureAcceptAny || probes["flaky"] != 3 {
+		t.Fatalf("Expected flaky honeypot to be flagged after 3 probes, got %v after %d", flaky, probes["flaky"])
+	}
+	down := results["down"]
+	if down == nil || !down.Success || !down.Unverified || probes["down"] != config.MaxRetries+1 {
+		t.Fatalf("Expected down target to be reported unverified after %d probes, got %v after %d", config.MaxRetries+1, down, probes["down"])
+	}
+	if down.ExtraInfo["verify_failure"] != brute.FailureTimeout.String() {
+		t.Fatalf("Expected verify failure %s, got %v", brute.FailureTimeout, down.ExtraInfo["verify_failure"])
+	}
+}